@@ -6,7 +6,21 @@ CREATE TABLE users
     password    BINARY(60)      NOT NULL,
     email       VARCHAR(255) UNIQUE      DEFAULT NULL,
     image       VARCHAR(255)    NOT NULL DEFAULT '',
-    created_at  TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP
+    created_at  TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    role        VARCHAR(16)     NOT NULL DEFAULT 'reader',
+    banned_at   TIMESTAMP                DEFAULT NULL,
+    history_enabled BOOLEAN     NOT NULL DEFAULT TRUE,
+    -- Null until the user opens the link sent to the email
+    email_verified_at TIMESTAMP          DEFAULT NULL,
+    -- The secret of the authenticator app, null while two-factor authentication is off
+    totp_secret VARBINARY(32)            DEFAULT NULL,
+    -- The secret waiting for the first code from the app to be confirmed
+    totp_pending_secret VARBINARY(32)    DEFAULT NULL,
+    -- The last time step a code was accepted for, a code cannot be used twice
+    totp_last_counter BIGINT    NOT NULL DEFAULT 0,
+    -- The codes tried since the last accepted one, the codes are locked once it reaches the limit
+    totp_failed_attempts INT    NOT NULL DEFAULT 0,
+    totp_locked_until TIMESTAMP          DEFAULT NULL
 );
 
 CREATE INDEX users_username_index ON users (username);
@@ -53,12 +67,16 @@ CREATE TABLE novels
     clicks       INT           NOT NULL DEFAULT 0,
     adult        BOOLEAN       NOT NULL DEFAULT FALSE,
     status       INT           NOT NULL DEFAULT 1,
-    visibility   INT           NOT NULL DEFAULT 1
+    visibility   INT           NOT NULL DEFAULT 1,
+    deleted_at   TIMESTAMP     NULL     DEFAULT NULL,
+    -- Set when a moderator removed the novel, the author cannot restore it
+    taken_down_at TIMESTAMP    NULL     DEFAULT NULL
 );
 
 CREATE FULLTEXT INDEX novels_title_FTS_index ON novels (title);
 CREATE INDEX novels_author_index ON novels (author);
 CREATE INDEX novels_status_id_index ON novels (status);
+CREATE INDEX novels_deleted_at_index ON novels (deleted_at);
 
 CREATE TABLE tags
 (
@@ -68,7 +86,7 @@ CREATE TABLE tags
     created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE INDEX tags_name_index ON tags (name);
+CREATE UNIQUE INDEX tags_name_index ON tags (name);
 
 CREATE TABLE novel_tags
 (
@@ -91,7 +109,8 @@ CREATE TABLE volumes
     created_at  TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at  TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     views       INT           NOT NULL DEFAULT 0,
-    visibility  INT           NOT NULL DEFAULT 1
+    visibility  INT           NOT NULL DEFAULT 1,
+    position    INT           NOT NULL DEFAULT 0
 );
 
 CREATE INDEX volumes_novel_id_index ON volumes (novel_id);
@@ -106,7 +125,9 @@ CREATE TABLE chapters
     created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     views      INT          NOT NULL DEFAULT 0,
-    visibility INT          NOT NULL DEFAULT 1
+    visibility INT          NOT NULL DEFAULT 1,
+    position   INT          NOT NULL DEFAULT 0,
+    word_count INT          NOT NULL DEFAULT 0
 );
 
 CREATE INDEX chapters_volume_id_index ON chapters (volume_id);
@@ -122,16 +143,20 @@ CREATE TABLE comments
     updated_at TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
-CREATE INDEX comments_to_id_index ON comments (to_id);
+CREATE INDEX comments_to_id_index ON comments (to_id, created_at);
 CREATE INDEX comments_user_id_index ON comments (user_id);
 
 CREATE TABLE images
 (
-    id         INT PRIMARY KEY AUTO_INCREMENT,
-    user_id    BINARY(16)   NOT NULL,
-    novel_id   BINARY(16)   NOT NULL,
-    url        VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+    id             INT PRIMARY KEY AUTO_INCREMENT,
+    user_id        BINARY(16)   NOT NULL,
+    novel_id       BINARY(16)            DEFAULT NULL,
+    url            VARCHAR(255) NOT NULL,
+    storage_key    VARCHAR(255) UNIQUE   DEFAULT NULL,
+    content_type   VARCHAR(32)  NOT NULL DEFAULT '',
+    size           INT          NOT NULL DEFAULT 0,
+    variants_ready BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at     TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX images_user_id_index ON images (user_id);
@@ -144,6 +169,18 @@ CREATE TABLE follows_user
     PRIMARY KEY (from_id, to_id)
 );
 
+CREATE INDEX follows_user_to_id_index ON follows_user (to_id);
+
+CREATE TABLE blocks
+(
+    from_id    BINARY(16) NOT NULL,
+    to_id      BINARY(16) NOT NULL,
+    created_at TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (from_id, to_id)
+);
+
+CREATE INDEX blocks_to_id_index ON blocks (to_id);
+
 CREATE TABLE follows_novel
 (
     user_id  BINARY(16) NOT NULL,
@@ -151,6 +188,47 @@ CREATE TABLE follows_novel
     PRIMARY KEY (user_id, novel_id)
 );
 
+CREATE INDEX follows_novel_novel_id_index ON follows_novel (novel_id);
+
+CREATE TABLE ratings
+(
+    user_id    BINARY(16) NOT NULL,
+    novel_id   BINARY(16) NOT NULL,
+    rating     TINYINT    NOT NULL,
+    created_at TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, novel_id)
+);
+
+CREATE INDEX ratings_novel_id_index ON ratings (novel_id);
+
+CREATE TABLE reviews
+(
+    id            BINARY(16) PRIMARY KEY,
+    user_id       BINARY(16)    NOT NULL,
+    novel_id      BINARY(16)    NOT NULL,
+    title         VARCHAR(255)  NOT NULL,
+    content       TEXT          NOT NULL,
+    spoiler       BOOLEAN       NOT NULL DEFAULT FALSE,
+    helpful_count INT           NOT NULL DEFAULT 0,
+    reply         VARCHAR(5000) NULL     DEFAULT NULL,
+    replied_at    TIMESTAMP     NULL     DEFAULT NULL,
+    created_at    TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, novel_id)
+);
+
+CREATE INDEX reviews_novel_id_index ON reviews (novel_id);
+
+CREATE TABLE review_votes
+(
+    review_id BINARY(16) NOT NULL,
+    user_id   BINARY(16) NOT NULL,
+    PRIMARY KEY (review_id, user_id)
+);
+
+CREATE INDEX review_votes_user_id_index ON review_votes (user_id);
+
 CREATE TABLE report_reason
 (
     id     int PRIMARY KEY AUTO_INCREMENT,
@@ -167,22 +245,150 @@ VALUES ('Other');
 
 CREATE TABLE reports
 (
-    id         INT PRIMARY KEY AUTO_INCREMENT,
-    user_id    BINARY(16) NOT NULL,
-    to_id      BINARY(16) NOT NULL,
-    reason_id  INT        NOT NULL,
-    created_at TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP
+    id            INT PRIMARY KEY AUTO_INCREMENT,
+    user_id       BINARY(16)    NOT NULL,
+    to_id         BINARY(16)    NOT NULL,
+    target_type   VARCHAR(16)   NOT NULL,
+    reason_id     INT           NOT NULL,
+    details       VARCHAR(1000) NOT NULL DEFAULT '',
+    resolution_id INT                    DEFAULT NULL,
+    created_at    TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX reported_user_id_index ON reports (user_id);
-CREATE INDEX reported_novel_id_index ON reports (to_id);
+CREATE INDEX reported_novel_id_index ON reports (to_id, resolution_id);
+CREATE INDEX reports_resolution_id_index ON reports (resolution_id);
+
+CREATE TABLE report_resolutions
+(
+    id           INT PRIMARY KEY AUTO_INCREMENT,
+    to_id        BINARY(16)    NOT NULL,
+    target_type  VARCHAR(16)   NOT NULL,
+    moderator_id BINARY(16)             DEFAULT NULL,
+    action       VARCHAR(16)   NOT NULL,
+    outcome      VARCHAR(1000) NOT NULL DEFAULT '',
+    report_count INT           NOT NULL DEFAULT 0,
+    created_at   TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX report_resolutions_to_id_index ON report_resolutions (to_id);
+CREATE INDEX report_resolutions_moderator_id_index ON report_resolutions (moderator_id);
+
+CREATE TABLE reading_progress
+(
+    user_id       BINARY(16) NOT NULL,
+    novel_id      BINARY(16) NOT NULL,
+    chapter_id    BINARY(16) NOT NULL,
+    scroll_offset DOUBLE     NOT NULL DEFAULT 0,
+    updated_at    TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, novel_id)
+);
+
+CREATE INDEX reading_progress_user_id_index ON reading_progress (user_id, updated_at);
+CREATE INDEX reading_progress_novel_id_index ON reading_progress (novel_id);
+CREATE INDEX reading_progress_chapter_id_index ON reading_progress (chapter_id);
+
+CREATE TABLE chapter_reads
+(
+    user_id    BINARY(16) NOT NULL,
+    chapter_id BINARY(16) NOT NULL,
+    novel_id   BINARY(16) NOT NULL,
+    read_at    TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, chapter_id)
+);
+
+CREATE INDEX chapter_reads_user_id_index ON chapter_reads (user_id, novel_id);
+CREATE INDEX chapter_reads_novel_id_index ON chapter_reads (novel_id);
+CREATE INDEX chapter_reads_chapter_id_index ON chapter_reads (chapter_id);
+
+CREATE TABLE bookmarks
+(
+    id         BINARY(16) PRIMARY KEY,
+    user_id    BINARY(16)    NOT NULL,
+    novel_id   BINARY(16)    NOT NULL,
+    chapter_id BINARY(16)    NOT NULL,
+    paragraph  INT           NOT NULL DEFAULT 0,
+    note       VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX bookmarks_user_id_index ON bookmarks (user_id);
+CREATE INDEX bookmarks_novel_id_index ON bookmarks (novel_id);
+CREATE INDEX bookmarks_chapter_id_index ON bookmarks (chapter_id);
+
+CREATE TABLE shelves
+(
+    id         BINARY(16) PRIMARY KEY,
+    user_id    BINARY(16)  NOT NULL,
+    name       VARCHAR(64) NOT NULL,
+    visibility INT         NOT NULL DEFAULT 1,
+    position   INT         NOT NULL DEFAULT 0,
+    created_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE shelf_novels
+(
+    shelf_id BINARY(16) NOT NULL,
+    novel_id BINARY(16) NOT NULL,
+    position INT        NOT NULL DEFAULT 0,
+    added_at TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (shelf_id, novel_id)
+);
+
+CREATE INDEX shelf_novels_novel_id_index ON shelf_novels (novel_id);
+
+CREATE TABLE reading_history
+(
+    id         BINARY(16) PRIMARY KEY,
+    user_id    BINARY(16) NOT NULL,
+    novel_id   BINARY(16) NOT NULL,
+    chapter_id BINARY(16) NOT NULL,
+    read_at    TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX reading_history_user_id_index ON reading_history (user_id, read_at);
+CREATE INDEX reading_history_novel_id_index ON reading_history (novel_id);
+CREATE INDEX reading_history_chapter_id_index ON reading_history (chapter_id);
 
 CREATE TABLE sessions
 (
     id          BINARY(16) PRIMARY KEY,
     user_id     BINARY(16)   NOT NULL,
     expires_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    device_name VARCHAR(255) NOT NULL
+    device_name VARCHAR(255) NOT NULL,
+    family_id   BINARY(16)   NOT NULL,
+    revoked_at  TIMESTAMP    NULL     DEFAULT NULL,
+    -- When the user logged in, kept by the sessions replacing this one
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ip_address  VARCHAR(45)  NOT NULL DEFAULT ''
+);
+
+CREATE INDEX sessions_user_id_index ON sessions (user_id);
+CREATE INDEX sessions_family_id_index ON sessions (family_id);
+
+-- The tokens sent by email, only the SHA-256 of the token is stored
+CREATE TABLE user_tokens
+(
+    token_hash BINARY(32) PRIMARY KEY,
+    user_id    BINARY(16)   NOT NULL,
+    purpose    VARCHAR(16)  NOT NULL,
+    -- The address the token was sent to
+    email      VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP    NOT NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX user_tokens_user_id_index ON user_tokens (user_id);
+
+-- The one-time codes replacing the authenticator app, only the SHA-256 is stored
+CREATE TABLE recovery_codes
+(
+    code_hash  BINARY(32) PRIMARY KEY,
+    user_id    BINARY(16) NOT NULL,
+    created_at TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE INDEX sessions_user_id_index ON sessions (user_id);
\ No newline at end of file
+CREATE INDEX recovery_codes_user_id_index ON recovery_codes (user_id);
\ No newline at end of file
@@ -3,11 +3,11 @@ package main
 import (
 	"Lightnovel/model"
 	"Lightnovel/model/repo"
-	"Lightnovel/route"
 	"context"
 	"database/sql"
 	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
 	"os"
 	"strconv"
 	"strings"
@@ -82,8 +82,17 @@ func main() {
 			user.Email,
 			user.Image,
 		)
+		for i, name := range model.DefaultShelves {
+			db.MustExec(
+				"INSERT INTO shelves (id, user_id, name, visibility, position) VALUES (?,?,?,?,?)",
+				repo.GetUUID(), user.ID, name, model.VisibilityPrivate, i+1,
+			)
+		}
 	}
 
+	// The first user administrates the site
+	db.MustExec("UPDATE users SET role = ? WHERE id = ?", model.RoleAdmin, users[0].ID)
+
 	tags := []model.Tag{
 		{
 			ID:          1,
@@ -183,6 +192,11 @@ func main() {
 			novel.Clicks,
 		)
 	}
+	db.MustExec(
+		"UPDATE users SET role = ? WHERE role = ? AND id IN (SELECT author FROM novels)",
+		model.RoleAuthor,
+		model.RoleReader,
+	)
 
 	novelTags := []model.NovelTags{
 		{
@@ -221,6 +235,7 @@ func main() {
 				Image:       novels[i].Image,
 				Visibility:  2,
 				Views:       50 + j,
+				Position:    j,
 			})
 		}
 		volumes = append(volumes, model.Volume{
@@ -231,11 +246,12 @@ func main() {
 			Description: "Description",
 			Image:       novels[i].Image,
 			Visibility:  1,
+			Position:    3,
 		})
 	}
 	for _, volume := range volumes {
 		db.MustExec(
-			"INSERT INTO volumes (id, novel_id, title, tagline, description, image, visibility, views) VALUES (?,?,?,?,?,?,?,?)",
+			"INSERT INTO volumes (id, novel_id, title, tagline, description, image, visibility, views, position) VALUES (?,?,?,?,?,?,?,?,?)",
 			volume.ID,
 			volume.NovelID,
 			volume.Title,
@@ -244,6 +260,7 @@ func main() {
 			volume.Image,
 			volume.Visibility,
 			volume.Views,
+			volume.Position,
 		)
 	}
 
@@ -257,6 +274,7 @@ func main() {
 				Content:    "# Content\n ## Content\n ### Content\n #### Content",
 				Visibility: 2,
 				Views:      50 + j,
+				Position:   1,
 			})
 		}
 		chapters = append(chapters, model.Chapter{
@@ -265,12 +283,13 @@ func main() {
 			Title:      "Chapter " + "3" + " of " + volumes[i*3].Title,
 			Content:    "# Content\n ## Content\n ### Content\n #### Content",
 			Visibility: 1,
+			Position:   2,
 		})
 	}
 	for _, chapter := range chapters {
 		db.MustExec(
-			"INSERT INTO chapters (id, volume_id, title, content, visibility) VALUES (?,?,?,?,?)",
-			chapter.ID, chapter.VolumeID, chapter.Title, chapter.Content, chapter.Visibility,
+			"INSERT INTO chapters (id, volume_id, title, content, visibility, position, word_count) VALUES (?,?,?,?,?,?,?)",
+			chapter.ID, chapter.VolumeID, chapter.Title, chapter.Content, chapter.Visibility, chapter.Position, model.CountWords(chapter.Content),
 		)
 	}
 
@@ -431,7 +450,7 @@ func SetupDatabase(db *sqlx.DB) {
 }
 
 func pwd(s string) []byte {
-	res, _ := route.PasswordHash(s)
+	res, _ := bcrypt.GenerateFromPassword([]byte(s), bcrypt.DefaultCost)
 	return res
 }
 
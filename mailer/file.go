@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File writes the emails as .eml files in a directory instead of sending them,
+// for the development where there is no mail server
+type File struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir string, from string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{dir: dir, from: from}, nil
+}
+
+func (f *File) Send(_ context.Context, message Message) error {
+	now := time.Now()
+	data, err := buildMessage(f.from, message, now)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := filepath.Join(
+		f.dir,
+		fmt.Sprintf("%v-%v.eml", now.UTC().Format("20060102-150405"), hex.EncodeToString(suffix)),
+	)
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		return err
+	}
+	log.Infof("Email %q to %v written to %v", message.Subject, message.To, name)
+	return nil
+}
@@ -0,0 +1,217 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_buildMessage(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data, err := buildMessage("Light novel <noreply@example.com>", Message{
+		To:      "reader@example.com",
+		Subject: "Vérifiez",
+		Body:    "Hello\n" + strings.Repeat("a", 100),
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, body, ok := strings.Cut(string(data), "\r\n\r\n")
+	if !ok {
+		t.Fatalf("no blank line between the header and the body: %q", data)
+	}
+	header += "\r\n"
+	for _, want := range []string{
+		"From: Light novel <noreply@example.com>",
+		"To: reader@example.com",
+		"Subject: =?utf-8?q?V=C3=A9rifiez?=",
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000",
+		"Content-Transfer-Encoding: quoted-printable",
+	} {
+		if !strings.Contains(header, want+"\r\n") {
+			t.Errorf("header %q does not contain %q", header, want)
+		}
+	}
+	for _, line := range strings.Split(body, "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("line longer than 76 characters: %q", line)
+		}
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Hello\r\n" + strings.Repeat("a", 100); string(decoded) != want {
+		t.Errorf("body = %q, want %q", decoded, want)
+	}
+
+	for _, message := range []Message{
+		{To: "reader@example.com\r\nBcc: other@example.com", Subject: "Hi"},
+		{To: "reader@example.com", Subject: "Hi\nBcc: other@example.com"},
+	} {
+		if _, err := buildMessage("noreply@example.com", message, now); !errors.Is(err, ErrBadHeader) {
+			t.Errorf("buildMessage(%q, %q) error = %v, want ErrBadHeader", message.To, message.Subject, err)
+		}
+	}
+	if _, err := buildMessage("noreply@example.com", Message{To: "not an address"}, now); err == nil {
+		t.Error("buildMessage accepted a bad address")
+	}
+}
+
+// A stand-in for a mail server speaking just enough SMTP to accept one message
+func serveSMTP(t *testing.T, listener net.Listener, received chan<- []string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Error(err)
+		close(received)
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	var commands []string
+	reply("220 localhost ready")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			received <- commands
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		commands = append(commands, line)
+		switch {
+		case strings.HasPrefix(line, "EHLO"):
+			reply("250 localhost")
+		case line == "DATA":
+			reply("354 go ahead")
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					received <- commands
+					return
+				}
+				dataLine = strings.TrimRight(dataLine, "\r\n")
+				if dataLine == "." {
+					break
+				}
+				commands = append(commands, dataLine)
+			}
+			reply("250 queued")
+		case line == "QUIT":
+			reply("221 bye")
+			received <- commands
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTP_Send(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	received := make(chan []string, 1)
+	go serveSMTP(t, listener, received)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	mailer := NewSMTP(SMTPConfig{Addr: listener.Addr().String(), From: "Light novel <noreply@example.com>"})
+	err = mailer.Send(ctx, Message{To: "Reader <reader@example.com>", Subject: "Reset", Body: "Your code"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := strings.Join(<-received, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<noreply@example.com>",
+		"RCPT TO:<reader@example.com>",
+		"Subject: Reset",
+		"Your code",
+		"QUIT",
+	} {
+		if !strings.Contains(commands, want) {
+			t.Errorf("the server did not receive %q in %q", want, commands)
+		}
+	}
+}
+
+func TestFile_Send(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFile(dir, "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mailer.Send(context.Background(), Message{To: "reader@example.com", Subject: "Verify", Body: "Your link"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("files = %v, %v, want one .eml file", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "To: reader@example.com\r\n") || !strings.Contains(string(data), "Your link") {
+		t.Errorf("unexpected file content %q", data)
+	}
+}
+
+type recordMailer struct {
+	sent chan Message
+}
+
+func (m *recordMailer) Send(_ context.Context, message Message) error {
+	m.sent <- message
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	mailer := &recordMailer{sent: make(chan Message, 2)}
+	queue := NewQueue(mailer, 2)
+	if !queue.Enqueue(func() (Message, bool) { return Message{}, false }) {
+		t.Fatal("Enqueue() = false on an empty queue")
+	}
+	if !queue.Enqueue(func() (Message, bool) { return Message{To: "reader@example.com"}, true }) {
+		t.Fatal("Enqueue() = false with room left")
+	}
+	if queue.Enqueue(func() (Message, bool) { return Message{To: "other@example.com"}, true }) {
+		t.Fatal("Enqueue() = true on a full queue")
+	}
+
+	go queue.Run()
+	select {
+	case message := <-mailer.sent:
+		if message.To != "reader@example.com" {
+			t.Errorf("sent to %q, want reader@example.com", message.To)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queued email was not sent")
+	}
+	select {
+	case message := <-mailer.sent:
+		t.Errorf("unexpected email to %q", message.To)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
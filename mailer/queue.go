@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"time"
+)
+
+const sendTimeout = 30 * time.Second
+
+// Queue sends the emails in the background with a fixed number of workers, the
+// emails are dropped when the queue is full. The emails can still be sent right
+// away with Send
+type Queue struct {
+	Mailer
+	jobs chan func() (Message, bool)
+}
+
+func NewQueue(mailer Mailer, queueSize int) *Queue {
+	return &Queue{
+		Mailer: mailer,
+		jobs:   make(chan func() (Message, bool), queueSize),
+	}
+}
+
+// Queue the job building the email, the email is not sent if the job returns
+// false. Return false if the queue is full
+func (q *Queue) Enqueue(job func() (Message, bool)) bool {
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Send the queued emails until the program exits, run it once for each worker
+func (q *Queue) Run() {
+	for job := range q.jobs {
+		message, ok := job()
+		if !ok {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := q.Send(ctx, message)
+		cancel()
+		if err != nil {
+			log.Error(err)
+		}
+	}
+}
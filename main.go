@@ -1,10 +1,15 @@
 package main
 
 import (
+	"Lightnovel/mailer"
 	"Lightnovel/middleware"
 	"Lightnovel/model/repo"
 	"Lightnovel/route"
+	"Lightnovel/storage"
+	"Lightnovel/thumbnail"
+	"Lightnovel/token"
 	"context"
+	"crypto/rand"
 	"github.com/go-sql-driver/mysql"
 	"github.com/gofiber/contrib/swagger"
 	"github.com/gofiber/fiber/v2"
@@ -31,6 +36,16 @@ func main() {
 		}
 	}()
 	database := repo.NewDatabase(db, time.Minute)
+	store := getStorage()
+	go runPeriodically(time.Hour, database.PurgeDeletedNovels)
+	go runPeriodically(time.Hour, database.DeleteExpiredUserTokens)
+	worker := thumbnail.NewWorker(&database, store, 100)
+	go worker.Run()
+	go runPeriodically(time.Hour, worker.EnqueuePending)
+	mailQueue := mailer.NewQueue(getMailer(), 100)
+	for i := 0; i < 4; i++ {
+		go mailQueue.Run()
+	}
 
 	app := fiber.New()
 	app.Use(recover2.New(recover2.Config{
@@ -60,7 +75,8 @@ func main() {
 	app.Use(logger.New(logger.ConfigDefault))
 	app.Get("/metrics", monitor.New())
 
-	authMiddleware := middleware.AddAuthenticationCheck(&database)
+	issuer := getTokenIssuer()
+	authMiddleware := middleware.AddAuthenticationCheck(issuer)
 	app.Use(authMiddleware)
 
 	swaggerRoute := app.Group("/swagger")
@@ -77,8 +93,12 @@ func main() {
 	apiRoute := app.Group("/api")
 	v1 := apiRoute.Group("/v1")
 
-	route.AddAccountRoutes(&v1, &database)
+	route.AddAccountRoutes(&v1, &database, store, issuer, mailQueue, os.Getenv("APP_URL"))
 	route.AddUploadRoutes(&v1, &database)
+	route.AddTagRoutes(&v1, &database)
+	route.AddReportRoutes(&v1, &database)
+	route.AddShelfRoutes(&v1, &database)
+	route.AddImageRoutes(&v1, &database, store, worker)
 
 	//data, _ := json.MarshalIndent(app.Stack(), "", "  ")
 	//fmt.Println(string(data))
@@ -86,6 +106,18 @@ func main() {
 	log.Fatal(app.Listen(":8080"))
 }
 
+// Run the task right away and then after every interval until the program exits
+func runPeriodically(interval time.Duration, task func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if !task() {
+			log.Warn("Periodic task failed, retry on next tick")
+		}
+		<-ticker.C
+	}
+}
+
 func getDBConnect() *sqlx.DB {
 	mysqlConfig := mysql.Config{
 		User:      os.Getenv("MYSQL_USER"),
@@ -111,3 +143,91 @@ func getDBConnect() *sqlx.DB {
 	}
 	return db
 }
+
+// Use the S3 compatible storage when STORAGE_BACKEND is s3, otherwise keep
+// the uploaded files in STORAGE_DIR
+func getStorage() storage.Storage {
+	if os.Getenv("STORAGE_BACKEND") == "s3" {
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	}
+
+	dir := os.Getenv("STORAGE_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+	local, err := storage.NewLocal(dir)
+	if err != nil {
+		panic(err)
+	}
+	return local
+}
+
+// Send the emails through the SMTP server at SMTP_ADDR when MAIL_BACKEND is
+// smtp, otherwise write them in MAIL_DIR
+func getMailer() mailer.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if os.Getenv("MAIL_BACKEND") == "smtp" {
+		return mailer.NewSMTP(mailer.SMTPConfig{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		})
+	}
+
+	dir := os.Getenv("MAIL_DIR")
+	if dir == "" {
+		dir = "mails"
+	}
+	if from == "" {
+		from = "noreply@localhost"
+	}
+	file, err := mailer.NewFile(dir, from)
+	if err != nil {
+		panic(err)
+	}
+	return file
+}
+
+// Sign the access tokens with the keys in JWT_KEYS, the algorithm is
+// JWT_ALGORITHM (HS256 or EdDSA). Without keys a random key is used, so the
+// access tokens stop working when the server restarts
+func getTokenIssuer() *token.Issuer {
+	algorithm := os.Getenv("JWT_ALGORITHM")
+	if algorithm == "" {
+		algorithm = token.AlgorithmHMAC
+	}
+
+	var keys []token.Key
+	spec := os.Getenv("JWT_KEYS")
+	if spec == "" {
+		log.Warn("JWT_KEYS is not set, signing the access tokens with a random key")
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+		key, err := token.NewHMACKey("random", secret)
+		if err != nil {
+			panic(err)
+		}
+		keys = append(keys, key)
+	} else {
+		var err error
+		keys, err = token.ParseKeys(algorithm, spec)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	issuer, err := token.NewIssuer(keys...)
+	if err != nil {
+		panic(err)
+	}
+	return issuer
+}
@@ -2,8 +2,10 @@ package middleware
 
 import (
 	"Lightnovel/model"
+	"Lightnovel/token"
 	"encoding/hex"
 	"github.com/gofiber/fiber/v2"
+	"strings"
 	"time"
 )
 
@@ -11,34 +13,72 @@ const (
 	KeyIsUserAuth  = "isUserAuth"
 	KeyUserSession = "userSession"
 	BodySession    = "session"
+	// The cookie holding the access token
+	CookieSession = "session"
+	// The cookie holding the refresh token, only sent to the account routes
+	CookieRefresh = "refresh"
+	BearerPrefix  = "Bearer "
 )
 
 func Unhex(s string) ([]byte, error) {
 	return hex.DecodeString(s[:model.IDHexLength])
 }
 
-func AddAuthenticationCheck(db model.DB) fiber.Handler {
+// Return the access token sent with the request, the Authorization header is
+// checked first, then the session cookie and finally the JSON body for the clients
+// made before the header and the cookie were supported
+func GetSessionString(c *fiber.Ctx) string {
+	if accessToken, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), BearerPrefix); ok {
+		return strings.TrimSpace(accessToken)
+	}
+
+	if cookie := c.Cookies(CookieSession); cookie != "" {
+		return cookie
+	}
+
+	var body model.IncludeSessionString
+	if err := c.BodyParser(&body); err != nil {
+		return ""
+	}
+	return body.Session
+}
+
+// Authenticate the request with the signed access token, the database is not
+// queried so a revoked session stays usable until its access token expires.
+// The session and the role from the token are kept for the next handlers
+func AddAuthenticationCheck(issuer *token.Issuer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var body model.IncludeSessionString
-		err := c.BodyParser(&body)
-		if err != nil || len(body.Session) != model.IDHexLength {
+		// The responses depend on the user, shared caches must not serve them to
+		// someone else. The handlers can still replace the cache control
+		c.Vary(fiber.HeaderAuthorization, fiber.HeaderCookie)
+		if c.Get(fiber.HeaderAuthorization) != "" || c.Cookies(CookieSession) != "" {
+			c.Set(fiber.HeaderCacheControl, "private")
+		}
+
+		claims, err := issuer.Verify(GetSessionString(c))
+		if err != nil {
 			c.Locals(KeyIsUserAuth, false)
 			return c.Next()
 		}
 
-		sessionInfo, err := Unhex(body.Session)
-		if err != nil {
+		userID, err := hex.DecodeString(claims.Subject)
+		if err != nil || len(userID) != model.IDBinLength {
 			c.Locals(KeyIsUserAuth, false)
 			return c.Next()
 		}
-		session, ok := db.GetSession(sessionInfo)
-		if !ok || session.ExpireAt.Before(time.Now()) {
+		sessionID, err := hex.DecodeString(claims.SessionID)
+		if err != nil || len(sessionID) != model.IDBinLength {
 			c.Locals(KeyIsUserAuth, false)
 			return c.Next()
 		}
 
 		c.Locals(KeyIsUserAuth, true)
-		c.Locals(KeyUserSession, session)
+		c.Locals(KeyUserSession, model.Session{
+			ID:       sessionID,
+			UserID:   userID,
+			ExpireAt: time.Unix(claims.ExpiresAt, 0),
+		})
+		c.Locals(KeyUserRole, model.Role(claims.Role))
 		return c.Next()
 	}
 }
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"Lightnovel/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+const KeyUserRole = "userRole"
+
+// Only let the request through if the user is logged in and their role has the
+// permission, must run after AddAuthenticationCheck which keeps the role from
+// the access token in KeyUserRole
+func RequirePermission(permission model.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(KeyIsUserAuth) != true {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		role, ok := c.Locals(KeyUserRole).(model.Role)
+		if !ok || !role.HasPermission(permission) {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		return c.Next()
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/jmoiron/sqlx"
+	"path"
+	"strings"
 	"time"
 )
 
@@ -19,22 +21,42 @@ const (
 	DisplayNameMinLength = 3
 	EmailMaxLength       = 255
 	ImageURLMaxLength    = 255
+	ImageMaxSize         = 3 << 20 // 3MB, below the default body limit
+	ImageMaxDimension    = 8000
 
-	TitleMaxLength        = 255
-	TaglineMaxLength      = 255
-	DescriptionMaxLength  = 5000
-	ContentMaxLength      = 16777215 // 16MB 2^24
-	CommentMaxLength      = 5000
-	ReportReasonMaxLength = 50
+	TitleMaxLength         = 255
+	TaglineMaxLength       = 255
+	DescriptionMaxLength   = 5000
+	ContentMaxLength       = 16777215 // 16MB 2^24
+	CommentMaxLength       = 5000
+	ReportReasonMaxLength  = 50
+	ReportDetailsMaxLength = 1000
+	ReportOutcomeMaxLength = 1000
+	BookmarkNoteMaxLength  = 1000
 
 	TagNameMaxLength        = 50
 	TagNameMinLength        = 2
 	TagDescriptionMaxLength = 300
+	NovelMaxTags            = 20
+
+	ShelfNameMinLength = 1
+	ShelfNameMaxLength = 64
 
 	DeviceNameMinLength = 0
 	DeviceNameMaxLength = 255
 
+	RatingMin = 1
+	RatingMax = 5
+
+	ReviewMaxLength      = 20000
+	ReviewReplyMaxLength = 5000
+
 	PageSize = 20
+
+	// Codes of the two-factor authentication that can be tried before they are
+	// refused for TwoFactorLockDuration
+	TwoFactorMaxAttempts  = 5
+	TwoFactorLockDuration = 15 * time.Minute
 )
 
 type NovelStatusID int
@@ -99,6 +121,8 @@ const (
 	OrderByUpdateAt  OrderBy = "updated_at"
 	OrderByViews     OrderBy = "views"
 	OrderByTitle     OrderBy = "title"
+	// The order the owner gave to the novels of a shelf, only valid for shelves
+	OrderByShelfPosition OrderBy = "position"
 )
 
 func (order OrderBy) Validate() bool {
@@ -111,6 +135,197 @@ func (order OrderBy) Validate() bool {
 	return true
 }
 
+// The path the uploaded images are served from, the key of the image is appended to it
+const ImageURLPath = "/api/v1/image/"
+
+type ImageVariant string
+
+const (
+	ImageSmall  ImageVariant = "small"
+	ImageMedium ImageVariant = "medium"
+	ImageLarge  ImageVariant = "large"
+)
+
+var ImageVariantList = []ImageVariant{ImageSmall, ImageMedium, ImageLarge}
+
+// Return the longest side of the variant in pixels, 0 for an unknown variant
+func (v ImageVariant) MaxSize() int {
+	switch v {
+	case ImageSmall:
+		return 160
+	case ImageMedium:
+		return 480
+	case ImageLarge:
+		return 1080
+	default:
+		return 0
+	}
+}
+
+// Return the storage key of the variant of the uploaded image, variants are always JPEG
+func (v ImageVariant) Key(key string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + "_" + string(v) + ".jpg"
+}
+
+// Split the storage key of a variant into the name of the uploaded image without
+// its extension and the variant, ok is false if the key is not a variant key
+func ParseImageVariantKey(key string) (name string, variant ImageVariant, ok bool) {
+	if !strings.HasSuffix(key, ".jpg") {
+		return "", "", false
+	}
+	i := strings.LastIndex(key, "_")
+	if i <= 0 {
+		return "", "", false
+	}
+	variant = ImageVariant(strings.TrimSuffix(key[i+1:], ".jpg"))
+	if variant.MaxSize() == 0 {
+		return "", "", false
+	}
+	return key[:i], variant, true
+}
+
+type ReviewSort string
+
+const (
+	ReviewSortHelpful ReviewSort = "helpful"
+	ReviewSortNewest  ReviewSort = "newest"
+)
+
+func (sort ReviewSort) Validate() bool {
+	if sort != ReviewSortHelpful && sort != ReviewSortNewest {
+		return false
+	}
+	return true
+}
+
+type Role string
+
+const (
+	RoleReader    Role = "reader"
+	RoleAuthor    Role = "author"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+type TokenPurpose string
+
+const (
+	TokenVerifyEmail   TokenPurpose = "verify_email"
+	TokenResetPassword TokenPurpose = "reset_password"
+	// Given after the password when the two-factor authentication is enabled,
+	// the login is finished by sending it with a code
+	TokenLoginChallenge TokenPurpose = "login_challenge"
+)
+
+// How long a new token cannot be sent after the previous one, so the inbox of
+// the user cannot be flooded
+func (purpose TokenPurpose) Cooldown() time.Duration {
+	if purpose == TokenLoginChallenge {
+		return 0
+	}
+	return 5 * time.Minute
+}
+
+// How long the token can be used
+func (purpose TokenPurpose) Duration() time.Duration {
+	switch purpose {
+	case TokenResetPassword:
+		return time.Hour
+	case TokenLoginChallenge:
+		return 5 * time.Minute
+	default:
+		return 24 * time.Hour
+	}
+}
+
+type Permission string
+
+const (
+	// Review the reports and resolve them
+	PermissionModerate Permission = "moderate"
+	// Ban and unban users whose role is below their own
+	PermissionBanUsers Permission = "ban_users"
+	// Create, update and merge tags
+	PermissionManageTags Permission = "manage_tags"
+	// Change the role of other users
+	PermissionManageRoles Permission = "manage_roles"
+)
+
+// The permissions of every role, readers and authors only act on their own content
+var rolePermissions = map[Role][]Permission{
+	RoleModerator: {PermissionModerate, PermissionBanUsers},
+	RoleAdmin: {
+		PermissionModerate,
+		PermissionBanUsers,
+		PermissionManageTags,
+		PermissionManageRoles,
+	},
+}
+
+func (role Role) Validate() bool {
+	if role != RoleReader &&
+		role != RoleAuthor &&
+		role != RoleModerator &&
+		role != RoleAdmin {
+		return false
+	}
+	return true
+}
+
+func (role Role) HasPermission(permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the rank of the role, a user can only ban users of a lower rank
+func (role Role) Rank() int {
+	switch role {
+	case RoleAuthor:
+		return 1
+	case RoleModerator:
+		return 2
+	case RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// The kind of content a report is about
+type ReportTarget string
+
+const (
+	ReportTargetUser    ReportTarget = "user"
+	ReportTargetNovel   ReportTarget = "novel"
+	ReportTargetChapter ReportTarget = "chapter"
+	ReportTargetComment ReportTarget = "comment"
+)
+
+// How the moderator closed the reports on a target
+type ReportAction string
+
+const (
+	// The report was valid and the issue was dealt with otherwise
+	ReportActionResolve ReportAction = "resolve"
+	// The report was not valid
+	ReportActionDismiss ReportAction = "dismiss"
+	// The reported content is removed or the reported user is banned
+	ReportActionTakeAction ReportAction = "action"
+)
+
+func (action ReportAction) Validate() bool {
+	if action != ReportActionResolve &&
+		action != ReportActionDismiss &&
+		action != ReportActionTakeAction {
+		return false
+	}
+	return true
+}
+
 type FiltersAndSortNovel struct {
 	SortOrder  SortOrder `db:"sort_order"`
 	OrderBy    OrderBy   `db:"order_by"`
@@ -123,6 +338,8 @@ type FiltersAndSortNovel struct {
 	FromDate   time.Time `db:"from_date"`
 	ToDate     time.Time `db:"to_date"`
 	Status     NovelStatusID
+	// The user looking for novels, the novels of the users they blocked are left out
+	ViewerID []byte `db:"viewer_id" swaggerignore:"true"`
 }
 
 var DefaultFiltersAndSort = FiltersAndSortNovel{
@@ -178,6 +395,9 @@ func (f *FiltersAndSortNovel) ConstructQuery() (string, []interface{}) {
 	if f.ToDate != DefaultFiltersAndSort.ToDate {
 		res += " AND novels.created_at <= :to_date"
 	}
+	if len(f.ViewerID) != 0 {
+		res += " AND novels.author NOT IN (SELECT to_id FROM blocks WHERE from_id = :viewer_id)"
+	}
 	for _, tag := range f.Tag {
 		res += fmt.Sprintf(" AND FIND_IN_SET(%v, tag_groupconcat)", tag)
 	}
@@ -188,7 +408,12 @@ func (f *FiltersAndSortNovel) ConstructQuery() (string, []interface{}) {
 	if !f.SortOrder.Validate() {
 		f.SortOrder = DefaultFiltersAndSort.SortOrder
 	}
-	res += fmt.Sprintf(" ORDER BY :order_by %v, novels.id ASC", f.SortOrder)
+	if f.OrderBy == OrderByShelfPosition {
+		// The query must join the shelf_novels table
+		res += fmt.Sprintf(" ORDER BY shelf_novels.position %v, novels.id ASC", f.SortOrder)
+	} else {
+		res += fmt.Sprintf(" ORDER BY :order_by %v, novels.id ASC", f.SortOrder)
+	}
 	res += fmt.Sprintf(" LIMIT %v OFFSET %v", PageSize*f.Page, PageSize*(f.Page-1))
 	resQuery, args, err := sqlx.Named(res, f)
 	if err != nil {
@@ -198,3 +423,6 @@ func (f *FiltersAndSortNovel) ConstructQuery() (string, []interface{}) {
 	//log.Debug(resQuery)
 	return resQuery, args
 }
+
+// The shelves every user starts with
+var DefaultShelves = []string{"Reading", "Plan to read", "Dropped"}
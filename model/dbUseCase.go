@@ -1,9 +1,15 @@
 package model
 
+import "time"
+
 type DB interface {
-	CreateSession(userID []byte, deviceName string) (SessionInfo, bool)
+	CreateSession(userID []byte, deviceName string, ipAddress string) (SessionInfo, bool)
 	GetSession(sessionID []byte) (Session, bool)
+	GetSessionByFamily(familyID []byte) (Session, bool)
+	GetUserSessions(userID []byte, currentSessionID []byte) []SessionView
+	RefreshSession(sessionID []byte, ipAddress string) (Session, SessionInfo, bool)
 	DeleteSession(sessionID []byte) bool
+	DeleteOtherSessions(userID []byte, currentSessionID []byte) bool
 	DeleteExpiredSessions() bool
 	DeleteAllSessions(userID []byte) bool
 	ExtendSessionLifetime(sessionID []byte) bool
@@ -13,16 +19,48 @@ type DB interface {
 	GetUserView(username string) (UserView, bool)
 	GetUserViewByID(userID []byte) (UserView, bool)
 	GetUserByID(userID []byte) (User, bool)
+	GetUserByEmail(email string) (User, bool)
 	GetUserMetadataSmall(userID []byte) (UserMetadataSmall, bool)
 	FindUsers(username string, page uint) []UserMetadataSmall
-	DeleteUser(userID []byte) bool
+	DeleteUser(userID []byte, novelsHeirID []byte) bool
 	UpdateUserMetadata(userID []byte, args *UserMetadata) bool
 	UpdateUserPassword(userID []byte, newPassword []byte) bool
+	VerifyUserEmail(userID []byte, email string) bool
+	CreateUserToken(userID []byte, purpose TokenPurpose, email string) (string, bool)
+	ConsumeUserToken(token string, purpose TokenPurpose) (UserToken, bool)
+	ResetPassword(token string, newPassword []byte) bool
+
+	SetPendingTOTPSecret(userID []byte, secret []byte) bool
+	EnableTOTP(userID []byte, secret []byte, counter int64, recoveryCodes []string) bool
+	DisableTOTP(userID []byte) bool
+	UseTOTPCounter(userID []byte, counter int64) bool
+	StartTOTPAttempt(userID []byte) bool
+	ResetTOTPAttempts(userID []byte) bool
+	ReplaceRecoveryCodes(userID []byte, recoveryCodes []string) bool
+	ConsumeRecoveryCode(userID []byte, recoveryCode string) bool
+	CountRecoveryCodes(userID []byte) int
+	SetUserRole(userID []byte, role Role) bool
+	BanUser(userID []byte) bool
+	UnbanUser(userID []byte) bool
 
 	GetFollowedUser(userID []byte) []UserMetadataSmall
 	GetFollowedNovel(userID []byte, filtersAndSort *FiltersAndSortNovel) []NovelMetadataSmall
+	FollowUser(fromID []byte, toID []byte) bool
+	UnfollowUser(fromID []byte, toID []byte) bool
+	IsFollowingUser(fromID []byte, toID []byte) bool
+	GetUserFollowers(userID []byte, page uint) []UserMetadataSmall
+	FollowNovel(userID []byte, novelID []byte) bool
+	UnfollowNovel(userID []byte, novelID []byte) bool
+	IsFollowingNovel(userID []byte, novelID []byte) bool
+	GetNovelFollowers(novelID []byte, page uint) []UserMetadataSmall
+
+	BlockUser(fromID []byte, toID []byte) bool
+	UnblockUser(fromID []byte, toID []byte) bool
+	IsBlocking(fromID []byte, toID []byte) bool
+	GetBlockedUsers(userID []byte, page uint) []UserMetadataSmall
 
 	CreateNovel(args *NovelMetadata) ([]byte, bool)
+	GetNovel(novelID []byte) (Novel, bool)
 	GetNovelView(novelID []byte) (NovelView, bool)
 	FindNovels(filtersAndSort *FiltersAndSortNovel) []NovelMetadataSmall
 	UpdateNovelMetadata(novelID []byte, args *NovelMetadata) bool
@@ -31,4 +69,111 @@ type DB interface {
 		filtersAndSort *FiltersAndSortNovel,
 		isSelf bool,
 	) []NovelMetadataSmall
+	DeleteNovel(novelID []byte) bool
+	GetDeletedNovel(novelID []byte) (Novel, bool)
+	RestoreNovel(novelID []byte) bool
+	PurgeDeletedNovels() bool
+
+	CreateVolume(novelID []byte, args *VolumeMetadata) ([]byte, bool)
+	GetVolume(volumeID []byte) (Volume, bool)
+	GetVolumeView(volumeID []byte) (VolumeView, bool)
+	GetNovelVolumes(novelID []byte, isAuthor bool) []VolumeView
+	UpdateVolumeMetadata(volumeID []byte, args *VolumeMetadata) bool
+	DeleteVolume(volumeID []byte) bool
+	ReorderVolumes(novelID []byte, volumeIDs [][]byte) bool
+
+	CreateChapter(volumeID []byte, args *ChapterMetadata) ([]byte, bool)
+	GetChapter(chapterID []byte) (Chapter, bool)
+	GetChapterView(novelID []byte, chapterID []byte, isAuthor bool) (ChapterView, bool)
+	GetVolumeChapters(volumeID []byte, isAuthor bool) []ChapterMetadataSmall
+	UpdateChapter(chapterID []byte, args *ChapterMetadata) bool
+	DeleteChapter(chapterID []byte) bool
+	ReorderChapters(volumeID []byte, chapterIDs [][]byte) bool
+
+	GetNovelTOC(novelID []byte, isAuthor bool) (NovelTOC, bool)
+
+	SaveReadingProgress(userID []byte, novelID []byte, chapterID []byte, args *ProgressMetadata) bool
+	GetReadingProgress(userID []byte, novelID []byte) (ReadingProgress, bool)
+	GetContinueReading(userID []byte, page uint) []ReadingProgressView
+	MarkChapterRead(userID []byte, novelID []byte, chapterID []byte) bool
+	UnmarkChapterRead(userID []byte, chapterID []byte) bool
+	GetReadChapterIDs(userID []byte, novelID []byte) []string
+
+	CreateBookmark(userID []byte, novelID []byte, chapterID []byte, args *BookmarkMetadata) ([]byte, bool)
+	GetBookmark(bookmarkID []byte) (Bookmark, bool)
+	GetBookmarks(userID []byte) []BookmarkGroup
+	DeleteBookmark(bookmarkID []byte) bool
+
+	CreateShelf(userID []byte, args *ShelfMetadata) ([]byte, bool)
+	GetShelf(shelfID []byte) (Shelf, bool)
+	GetShelfByName(userID []byte, name string) (Shelf, bool)
+	GetUserShelves(userID []byte, includePrivate bool) []ShelfView
+	UpdateShelf(shelfID []byte, args *ShelfMetadata) bool
+	DeleteShelf(shelfID []byte) bool
+	ReorderShelves(userID []byte, shelfIDs [][]byte) bool
+	AddNovelToShelf(shelfID []byte, novelID []byte) bool
+	RemoveNovelFromShelf(shelfID []byte, novelID []byte) bool
+	GetShelfNovelIDs(shelfID []byte) []string
+	GetShelfNovels(shelfID []byte, filtersAndSort *FiltersAndSortNovel) []NovelMetadataSmall
+	ReorderShelfNovels(shelfID []byte, novelIDs [][]byte) bool
+
+	RecordHistory(userID []byte, novelID []byte, chapterID []byte) bool
+	GetHistory(userID []byte, from time.Time, to time.Time, page uint) []HistoryEntryView
+	GetHistoryEntry(entryID []byte) (HistoryEntry, bool)
+	DeleteHistoryEntry(entryID []byte) bool
+	ClearHistory(userID []byte) bool
+	SetHistoryEnabled(userID []byte, enabled bool) bool
+
+	RateNovel(userID []byte, novelID []byte, rating int) bool
+	DeleteRating(userID []byte, novelID []byte) bool
+	GetUserRating(userID []byte, novelID []byte) int
+
+	CreateReview(userID []byte, novelID []byte, args *ReviewMetadata) ([]byte, bool)
+	GetReview(reviewID []byte) (Review, bool)
+	GetUserReview(userID []byte, novelID []byte) (Review, bool)
+	GetNovelReviews(novelID []byte, sort ReviewSort, page uint) []ReviewView
+	UpdateReview(reviewID []byte, args *ReviewMetadata) bool
+	DeleteReview(reviewID []byte) bool
+	VoteReviewHelpful(reviewID []byte, userID []byte) bool
+	UnvoteReviewHelpful(reviewID []byte, userID []byte) bool
+	ReplyReview(reviewID []byte, reply string) bool
+	DeleteReviewReply(reviewID []byte) bool
+
+	CreateTag(args *TagMetadata) (int, bool)
+	GetTag(tagID int) (Tag, bool)
+	GetTagByName(name string) (Tag, bool)
+	FindTags(search string, page uint) []Tag
+	TagsExist(tagIDs []int) bool
+	UpdateTag(tagID int, args *TagMetadata) bool
+	MergeTags(fromID int, toID int) bool
+	SetNovelTags(novelID []byte, tagIDs []int) bool
+
+	CreateImage(
+		userID []byte,
+		novelID []byte,
+		storageKey string,
+		contentType string,
+		size int,
+		url string,
+	) (int, bool)
+	GetImageByKey(storageKey string) (Image, bool)
+	GetImageByKeys(storageKeys []string) (Image, bool)
+	GetUserImageKeys(userID []byte) []string
+	GetPendingImageKeys() ([]string, bool)
+	SetImageVariantsReady(storageKey string) bool
+
+	CreateComment(toID []byte, userID []byte, args *CommentMetadata) ([]byte, bool)
+	GetComment(commentID []byte) (Comment, bool)
+	GetCommentRootTarget(targetID []byte) ([]byte, bool)
+	GetComments(toID []byte, viewerID []byte, cursor *CommentCursor) ([]CommentView, *CommentCursor)
+	UpdateComment(commentID []byte, args *CommentMetadata) bool
+	DeleteComment(commentID []byte) bool
+
+	GetReportReasons() []ReportReason
+	HasOpenReport(userID []byte, toID []byte) bool
+	CreateReport(userID []byte, toID []byte, targetType ReportTarget, args *ReportMetadata) bool
+	GetReportQueue(page uint) []ReportGroup
+	GetReportGroup(toID []byte) (ReportGroup, bool)
+	ResolveReports(toID []byte, moderatorID []byte, action ReportAction, outcome string) (bool, bool)
+	GetReportResolutions(toID []byte) []ReportResolutionView
 }
@@ -13,6 +13,21 @@ type User struct {
 	Email       sql.NullString `json:"email"`
 	Image       string         `json:"image"`
 	CreatedAt   time.Time      `json:"created_at"  db:"created_at"`
+	Role        Role           `json:"role"`
+	// Null if the user is not banned
+	BannedAt sql.NullTime `json:"-" db:"banned_at"`
+	// Whether the chapters the user opens are recorded in their reading history
+	HistoryEnabled bool `json:"-" db:"history_enabled"`
+	// Null until the user verifies their email
+	EmailVerifiedAt sql.NullTime `json:"-" db:"email_verified_at"`
+	// Nil while the two-factor authentication is disabled
+	TOTPSecret        []byte `json:"-" db:"totp_secret"`
+	TOTPPendingSecret []byte `json:"-" db:"totp_pending_secret"`
+	TOTPLastCounter   int64  `json:"-" db:"totp_last_counter"`
+	// Codes tried since the last accepted one, the codes are refused until
+	// TOTPLockedUntil once it reaches TwoFactorMaxAttempts
+	TOTPFailedAttempts int          `json:"-" db:"totp_failed_attempts"`
+	TOTPLockedUntil    sql.NullTime `json:"-" db:"totp_locked_until"`
 }
 
 type NovelStatus struct {
@@ -37,6 +52,9 @@ type Novel struct {
 	Adult       bool          `json:"adult"`
 	Status      NovelStatusID `json:"statusID"    db:"status"`
 	Visibility  VisibilityID  `json:"visibility"`
+	DeletedAt   sql.NullTime  `json:"-"           db:"deleted_at"`
+	// Null unless a moderator removed the novel
+	TakenDownAt sql.NullTime `json:"-" db:"taken_down_at"`
 }
 
 type Tag struct {
@@ -62,6 +80,7 @@ type Volume struct {
 	UpdateAt    time.Time    `json:"updateAt"    db:"updated_at"`
 	Views       int          `json:"views"`
 	Visibility  VisibilityID `json:"visibility"`
+	Position    int          `json:"position"`
 }
 
 type Chapter struct {
@@ -73,6 +92,8 @@ type Chapter struct {
 	UpdateAt   time.Time    `json:"updateAt"   db:"updated_at"`
 	Views      int          `json:"views"`
 	Visibility VisibilityID `json:"visibility"`
+	Position   int          `json:"position"`
+	WordCount  int          `json:"wordCount"  db:"word_count"`
 }
 
 type Comment struct {
@@ -84,14 +105,89 @@ type Comment struct {
 	UpdateAt time.Time `json:"updateAt" db:"updated_at"`
 }
 
+// The position of the last comment of a page, the next page starts right after it
+type CommentCursor struct {
+	CreateAt time.Time
+	ID       []byte
+}
+
 type Image struct {
-	ID       int       `json:"id"`
-	UserID   []byte    `json:"userId"   db:"user_id"`
-	NovelID  []byte    `json:"novelId"  db:"novel_id"`
-	Url      string    `json:"url"`
+	ID      int    `json:"id"`
+	UserID  []byte `json:"userId"      db:"user_id"`
+	NovelID []byte `json:"novelId"     db:"novel_id"`
+	Url     string `json:"url"`
+	// The key of the uploaded file in the storage, null for external images
+	StorageKey  sql.NullString `json:"-"           db:"storage_key"`
+	ContentType string         `json:"contentType" db:"content_type"`
+	Size        int            `json:"size"`
+	// Whether the resized variants of the uploaded image are in the storage
+	VariantsReady bool      `json:"variantsReady" db:"variants_ready"`
+	CreateAt      time.Time `json:"createAt"      db:"created_at"`
+}
+
+// The user with from_id blocked the user with to_id
+type Block struct {
+	FromID   []byte    `json:"fromId"   db:"from_id"`
+	ToID     []byte    `json:"toId"     db:"to_id"`
 	CreateAt time.Time `json:"createAt" db:"created_at"`
 }
 
+// Where the user stopped reading the novel
+type ReadingProgress struct {
+	UserID    []byte `json:"userId"    db:"user_id"`
+	NovelID   []byte `json:"novelId"   db:"novel_id"`
+	ChapterID []byte `json:"chapterId" db:"chapter_id"`
+	// The fraction of the chapter scrolled, from 0 to 1
+	ScrollOffset float64   `json:"scrollOffset" db:"scroll_offset"`
+	UpdateAt     time.Time `json:"updateAt"     db:"updated_at"`
+}
+
+type ChapterRead struct {
+	UserID    []byte    `json:"userId"    db:"user_id"`
+	ChapterID []byte    `json:"chapterId" db:"chapter_id"`
+	NovelID   []byte    `json:"novelId"   db:"novel_id"`
+	ReadAt    time.Time `json:"readAt"    db:"read_at"`
+}
+
+// A position in a chapter saved by the user, the paragraph is the index of the
+// paragraph in the chapter content starting from 0
+type Bookmark struct {
+	ID        []byte    `json:"id"`
+	UserID    []byte    `json:"userId"    db:"user_id"`
+	NovelID   []byte    `json:"novelId"   db:"novel_id"`
+	ChapterID []byte    `json:"chapterId" db:"chapter_id"`
+	Paragraph int       `json:"paragraph"`
+	Note      string    `json:"note"`
+	CreateAt  time.Time `json:"createAt"  db:"created_at"`
+}
+
+// A named reading list of the user
+type Shelf struct {
+	ID         []byte       `json:"id"`
+	UserID     []byte       `json:"userId"     db:"user_id"`
+	Name       string       `json:"name"`
+	Visibility VisibilityID `json:"visibility"`
+	Position   int          `json:"position"`
+	CreateAt   time.Time    `json:"createAt"   db:"created_at"`
+	UpdateAt   time.Time    `json:"updateAt"   db:"updated_at"`
+}
+
+type ShelfNovel struct {
+	ShelfID  []byte    `json:"shelfId"  db:"shelf_id"`
+	NovelID  []byte    `json:"novelId"  db:"novel_id"`
+	Position int       `json:"position"`
+	AddedAt  time.Time `json:"addedAt"  db:"added_at"`
+}
+
+// A chapter opened by the user
+type HistoryEntry struct {
+	ID        []byte    `json:"id"`
+	UserID    []byte    `json:"userId"    db:"user_id"`
+	NovelID   []byte    `json:"novelId"   db:"novel_id"`
+	ChapterID []byte    `json:"chapterId" db:"chapter_id"`
+	ReadAt    time.Time `json:"readAt"    db:"read_at"`
+}
+
 type FollowUser struct {
 	FromID []byte `json:"fromId" db:"from_id"`
 	ToID   []byte `json:"toId"   db:"to_id"`
@@ -102,12 +198,55 @@ type FollowNovel struct {
 	NovelID []byte `json:"novelId" db:"novel_id"`
 }
 
+type Rating struct {
+	UserID   []byte    `json:"userId"   db:"user_id"`
+	NovelID  []byte    `json:"novelId"  db:"novel_id"`
+	Rating   int       `json:"rating"`
+	CreateAt time.Time `json:"createAt" db:"created_at"`
+	UpdateAt time.Time `json:"updateAt" db:"updated_at"`
+}
+
+type Review struct {
+	ID           []byte         `json:"id"`
+	UserID       []byte         `json:"userId"       db:"user_id"`
+	NovelID      []byte         `json:"novelId"      db:"novel_id"`
+	Title        string         `json:"title"`
+	Content      string         `json:"content"`
+	Spoiler      bool           `json:"spoiler"`
+	HelpfulCount int            `json:"helpfulCount" db:"helpful_count"`
+	Reply        sql.NullString `json:"reply"`
+	RepliedAt    sql.NullTime   `json:"repliedAt"    db:"replied_at"`
+	CreateAt     time.Time      `json:"createAt"     db:"created_at"`
+	UpdateAt     time.Time      `json:"updateAt"     db:"updated_at"`
+}
+
+type ReviewVote struct {
+	ReviewID []byte `json:"reviewId" db:"review_id"`
+	UserID   []byte `json:"userId"   db:"user_id"`
+}
+
 type Report struct {
-	ID        int       `json:"id"`
-	UserID    []byte    `json:"userId"    db:"user_id"`
-	ToID      []byte    `json:"toId"      db:"to_id"`
-	ReasonID  int       `json:"reasonId"  db:"reason_id"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	ID         int          `json:"id"`
+	UserID     []byte       `json:"userId"     db:"user_id"`
+	ToID       []byte       `json:"toId"       db:"to_id"`
+	TargetType ReportTarget `json:"targetType" db:"target_type"`
+	ReasonID   int          `json:"reasonId"   db:"reason_id"`
+	Details    string       `json:"details"`
+	// The resolution which closed the report, null while the report is open
+	ResolutionID sql.NullInt64 `json:"-"         db:"resolution_id"`
+	CreatedAt    time.Time     `json:"createdAt" db:"created_at"`
+}
+
+// The outcome recorded when a moderator closes the open reports on a target
+type ReportResolution struct {
+	ID          int          `json:"id"`
+	ToID        []byte       `json:"toId"        db:"to_id"`
+	TargetType  ReportTarget `json:"targetType"  db:"target_type"`
+	ModeratorID []byte       `json:"moderatorId" db:"moderator_id"`
+	Action      ReportAction `json:"action"`
+	Outcome     string       `json:"outcome"`
+	ReportCount int          `json:"reportCount" db:"report_count"`
+	CreatedAt   time.Time    `json:"createdAt"   db:"created_at"`
 }
 
 type ReportReason struct {
@@ -115,9 +254,29 @@ type ReportReason struct {
 	Reason string `json:"reason"`
 }
 
+// A one-time token sent by email to the user, the token itself is only known
+// by the user
+type UserToken struct {
+	TokenHash []byte       `db:"token_hash"`
+	UserID    []byte       `db:"user_id"`
+	Purpose   TokenPurpose `db:"purpose"`
+	Email     string       `db:"email"`
+	ExpireAt  time.Time    `db:"expires_at"`
+	CreateAt  time.Time    `db:"created_at"`
+}
+
+// A session is a refresh token, renewing it replaces it with a new session of
+// the same family. Using a replaced session again revokes the whole family
 type Session struct {
 	ID         []byte    `db:"id"`
 	UserID     []byte    `db:"user_id"`
 	ExpireAt   time.Time `db:"expires_at"`
 	DeviceName string    `db:"device_name"`
+	// The ID of the first session of the family
+	FamilyID []byte `db:"family_id"`
+	// When the user logged in, the same for every session of the family
+	CreateAt time.Time `db:"created_at"`
+	// The last login or renewal of the session and the address it came from
+	LastUsedAt time.Time `db:"last_used_at"`
+	IPAddress  string    `db:"ip_address"`
 }
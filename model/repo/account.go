@@ -7,25 +7,43 @@ import (
 	"encoding/hex"
 	"errors"
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
 )
 
+// Create the user along with the default shelves
 func (db *Database) CreateUser(username string, password []byte) ([]byte, bool) {
 	userId := GetUUID()
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
-	_, err := db.db.ExecContext(
-		ctx,
-		"INSERT INTO users (id, username, password) VALUES (?,?,?)",
-		userId,
-		username,
-		password,
-	)
-	cancel()
+	defer cancel()
+	ok := db.withTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO users (id, username, password) VALUES (?,?,?)",
+			userId,
+			username,
+			password,
+		)
+		if err != nil {
+			return err
+		}
 
-	if err != nil && !errors.Is(err, context.Canceled) {
-		log.Error(err)
-		return userId, false
-	}
-	return userId, true
+		for i, name := range model.DefaultShelves {
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO shelves (id, user_id, name, visibility, position) VALUES (?,?,?,?,?)",
+				GetUUID(),
+				userId,
+				name,
+				model.VisibilityPrivate,
+				i+1,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return userId, ok
 }
 
 func (db *Database) GetUser(username string) (model.User, bool) {
@@ -51,7 +69,7 @@ func (db *Database) countUserNovel(userID []byte) int {
 	err := db.db.GetContext(
 		ctx,
 		&novelCount,
-		"SELECT COUNT(*) FROM novels WHERE author = ?",
+		"SELECT COUNT(*) FROM novels WHERE author = ? AND deleted_at IS NULL",
 		userID,
 	)
 	cancel()
@@ -76,6 +94,8 @@ func (db *Database) GetUserView(username string) (model.UserView, bool) {
 		NovelCount:    db.countUserNovel(user.ID),
 		FollowerCount: db.countUserFollowers(user.ID),
 		FollowedCount: db.countUserFollows(user.ID),
+		Role:          user.Role,
+		IsBanned:      user.BannedAt.Valid,
 	}
 
 	return userView, true
@@ -95,6 +115,8 @@ func (db *Database) GetUserViewByID(userID []byte) (model.UserView, bool) {
 		NovelCount:    db.countUserNovel(user.ID),
 		FollowerCount: db.countUserFollowers(user.ID),
 		FollowedCount: db.countUserFollows(user.ID),
+		Role:          user.Role,
+		IsBanned:      user.BannedAt.Valid,
 	}
 
 	return userView, true
@@ -112,6 +134,20 @@ func (db *Database) GetUserByID(userID []byte) (model.User, bool) {
 	return user, true
 }
 
+func (db *Database) GetUserByEmail(email string) (model.User, bool) {
+	var user model.User
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &user, "SELECT * FROM users WHERE email = ?", email)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return user, false
+	}
+	return user, true
+}
+
 func (db *Database) GetUserMetadataSmall(userID []byte) (model.UserMetadataSmall, bool) {
 	var userMetadataSmall struct {
 		ID          []byte
@@ -136,14 +172,121 @@ func (db *Database) GetUserMetadataSmall(userID []byte) (model.UserMetadataSmall
 		Username:    userMetadataSmall.Username,
 		Displayname: userMetadataSmall.Displayname.String,
 		Image:       userMetadataSmall.Image,
+		Images:      model.NewImageVariants(userMetadataSmall.Image),
 	}, true
 }
 
-func (db *Database) DeleteUser(userID []byte) bool {
+// Delete the user and every data of the user, the user's novels are transferred
+// to the heir if novelsHeirID is not nil, otherwise they are deleted as well
+func (db *Database) DeleteUser(userID []byte, novelsHeirID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		if err := deleteUserReviews(ctx, tx, userID); err != nil {
+			return err
+		}
+		if err := deleteUserRatings(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		var novelIDs [][]byte
+		err := tx.SelectContext(ctx, &novelIDs, "SELECT id FROM novels WHERE author = ?", userID)
+		if err != nil {
+			return err
+		}
+
+		if novelsHeirID != nil {
+			_, err = tx.ExecContext(
+				ctx,
+				`UPDATE images SET user_id = ?
+				WHERE novel_id IN (SELECT id FROM novels WHERE author = ?)`,
+				novelsHeirID,
+				userID,
+			)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(
+				ctx,
+				"UPDATE novels SET author = ? WHERE author = ?",
+				novelsHeirID,
+				userID,
+			)
+			if err != nil {
+				return err
+			}
+			if len(novelIDs) != 0 {
+				if err := promoteToAuthor(ctx, tx, novelsHeirID); err != nil {
+					return err
+				}
+			}
+		} else {
+			for _, novelID := range novelIDs {
+				if err := purgeNovel(ctx, tx, novelID); err != nil {
+					return err
+				}
+			}
+		}
+
+		var userCommentIDs [][]byte
+		err = tx.SelectContext(ctx, &userCommentIDs, "SELECT id FROM comments WHERE user_id = ?", userID)
+		if err != nil {
+			return err
+		}
+		replyIDs, err := getCommentTreeIDs(ctx, tx, userCommentIDs)
+		if err != nil {
+			return err
+		}
+		commentIDs := append(userCommentIDs, replyIDs...)
+
+		deletions := []struct {
+			query string
+			ids   [][]byte
+		}{
+			{"DELETE FROM reports WHERE to_id IN (?)", append(commentIDs, userID)},
+			{"DELETE FROM reports WHERE user_id IN (?)", [][]byte{userID}},
+			{"UPDATE report_resolutions SET moderator_id = NULL WHERE moderator_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM comments WHERE id IN (?)", commentIDs},
+			{"DELETE FROM images WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM follows_user WHERE from_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM follows_user WHERE to_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM blocks WHERE from_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM blocks WHERE to_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM reading_progress WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM chapter_reads WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM bookmarks WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM reading_history WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM follows_novel WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM shelf_novels WHERE shelf_id IN (SELECT id FROM shelves WHERE user_id IN (?))", [][]byte{userID}},
+			{"DELETE FROM shelves WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM sessions WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM user_tokens WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM recovery_codes WHERE user_id IN (?)", [][]byte{userID}},
+			{"DELETE FROM users WHERE id IN (?)", [][]byte{userID}},
+		}
+		for _, deletion := range deletions {
+			if err := execWithIDs(ctx, tx, deletion.query, deletion.ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (db *Database) UpdateUserMetadata(userID []byte, args *model.UserMetadata) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
 	_, err := db.db.ExecContext(
 		ctx,
-		"DELETE FROM users WHERE id = ?",
+		// A new email has to be verified again, the verification is reset before the email is changed
+		`UPDATE users
+		SET email_verified_at = IF(email <=> ?, email_verified_at, NULL),
+			username = ?, displayname = ?, email = ?, image = ?
+		WHERE id = ?`,
+		args.Email,
+		args.Username,
+		args.Displayname,
+		args.Email,
+		args.Image,
 		userID,
 	)
 	cancel()
@@ -154,15 +297,12 @@ func (db *Database) DeleteUser(userID []byte) bool {
 	return true
 }
 
-func (db *Database) UpdateUserMetadata(userID []byte, args *model.UserMetadata) bool {
+func (db *Database) UpdateUserPassword(userID []byte, newPassword []byte) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
 	_, err := db.db.ExecContext(
 		ctx,
-		"UPDATE users SET username = ?, displayname = ?, email = ?, image = ? WHERE id = ?",
-		args.Username,
-		args.Displayname,
-		args.Email,
-		args.Image,
+		"UPDATE users SET password = ? WHERE id = ?",
+		newPassword,
 		userID,
 	)
 	cancel()
@@ -173,19 +313,81 @@ func (db *Database) UpdateUserMetadata(userID []byte, args *model.UserMetadata)
 	return true
 }
 
-func (db *Database) UpdateUserPassword(userID []byte, newPassword []byte) bool {
+// Mark the email of the user as verified if it is still the email the token was sent to
+func (db *Database) VerifyUserEmail(userID []byte, email string) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
-	_, err := db.db.ExecContext(
+	result, err := db.db.ExecContext(
 		ctx,
-		"UPDATE users SET password = ? WHERE id = ?",
-		newPassword,
+		"UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = ? AND email = ?",
 		userID,
+		email,
 	)
 	cancel()
 	if err != nil {
 		log.Error(err)
 		return false
 	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return count == 1
+}
+
+func (db *Database) SetUserRole(userID []byte, role model.Role) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ?", role, userID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Make the reader an author once they publish a novel, other roles are kept
+func promoteToAuthor(ctx context.Context, tx *sqlx.Tx, userID []byte) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"UPDATE users SET role = ? WHERE id = ? AND role = ?",
+		model.RoleAuthor,
+		userID,
+		model.RoleReader,
+	)
+	return err
+}
+
+// Ban the user and log them out of every device, banning twice keeps the first ban time
+func (db *Database) BanUser(userID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		return banUser(ctx, tx, userID)
+	})
+}
+
+func banUser(ctx context.Context, tx *sqlx.Tx, userID []byte) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"UPDATE users SET banned_at = CURRENT_TIMESTAMP WHERE id = ? AND banned_at IS NULL",
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
+	return err
+}
+
+func (db *Database) UnbanUser(userID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(ctx, "UPDATE users SET banned_at = NULL WHERE id = ?", userID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
 	return true
 }
 
@@ -232,6 +434,7 @@ func (db *Database) GetFollowedUser(userID []byte) []model.UserMetadataSmall {
 			Username:    userMetaSmallRaw.Username,
 			Displayname: userMetaSmallRaw.Displayname.String,
 			Image:       userMetaSmallRaw.Image,
+			Images:      model.NewImageVariants(userMetaSmallRaw.Image),
 		})
 	}
 	return users
@@ -258,7 +461,8 @@ func (db *Database) GetFollowedNovel(
 		) AS TABLE1
 		ON TABLE1.novel_id = novels.id`
 	}
-	query += ` WHERE follows_novel.user_id = ? AND novels.visibility = ?` + filtersAndSortQuery
+	query += ` WHERE follows_novel.user_id = ? AND novels.visibility = ?
+		AND novels.deleted_at IS NULL` + filtersAndSortQuery
 
 	args := []interface{}{userID, model.VisibilityPublic}
 	if filtersAndSortArgs != nil {
@@ -290,25 +494,11 @@ func (db *Database) GetFollowedNovel(
 			log.Error(err)
 			return novels
 		}
-		authorMetadataSmall, ok := db.GetUserMetadataSmall(novel.Author)
+		novelMetadataSmall, ok := db.toNovelMetadataSmall(&novel)
 		if !ok {
 			return novels
 		}
-		novels = append(novels, model.NovelMetadataSmall{
-			ID:          hex.EncodeToString(novel.ID),
-			Title:       novel.Title,
-			Tagline:     novel.Tagline,
-			Description: novel.Description,
-			Author:      authorMetadataSmall,
-			Image:       novel.Image,
-			Language:    novel.Language,
-			TotalRating: novel.TotalRating,
-			RateCount:   novel.RateCount,
-			Adult:       novel.Adult,
-			Status:      novel.Status.String(),
-			Visibility:  novel.Visibility.String(),
-			Views:       novel.Views,
-		})
+		novels = append(novels, novelMetadataSmall)
 	}
 	return novels
 }
@@ -344,6 +534,7 @@ func (db *Database) FindUsers(username string, page uint) []model.UserMetadataSm
 			Username:    raw.Username,
 			Displayname: raw.Displayname.String,
 			Image:       raw.Image,
+			Images:      model.NewImageVariants(raw.Image),
 		})
 	}
 
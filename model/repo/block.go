@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+)
+
+// Make the user block the other user, the follows between them and the blocked
+// user's follows on the blocker's novels are removed. Blocking twice has no effect
+func (db *Database) BlockUser(fromID []byte, toID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT IGNORE INTO blocks (from_id, to_id) VALUES (?,?)",
+			fromID,
+			toID,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`DELETE FROM follows_user
+			WHERE (from_id = ? AND to_id = ?) OR (from_id = ? AND to_id = ?)`,
+			fromID,
+			toID,
+			toID,
+			fromID,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`DELETE FROM follows_novel
+			WHERE user_id = ? AND novel_id IN (SELECT id FROM novels WHERE author = ?)`,
+			toID,
+			fromID,
+		)
+		return err
+	})
+}
+
+func (db *Database) UnblockUser(fromID []byte, toID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"DELETE FROM blocks WHERE from_id = ? AND to_id = ?",
+		fromID,
+		toID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Check if the user with fromID blocked the user with toID
+func (db *Database) IsBlocking(fromID []byte, toID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	blocking := false
+	err := db.db.GetContext(
+		ctx,
+		&blocking,
+		"SELECT EXISTS(SELECT 1 FROM blocks WHERE from_id = ? AND to_id = ?)",
+		fromID,
+		toID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return blocking
+}
+
+func (db *Database) GetBlockedUsers(userID []byte, page uint) []model.UserMetadataSmall {
+	return db.getFollowers(
+		`SELECT users.id, users.username, users.displayname, users.image
+		FROM blocks INNER JOIN users
+		ON blocks.to_id = users.id
+		WHERE blocks.from_id = ?
+		ORDER BY users.username
+		LIMIT ? OFFSET ?`,
+		userID,
+		page,
+	)
+}
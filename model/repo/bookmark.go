@@ -0,0 +1,182 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+func (db *Database) CreateBookmark(
+	userID []byte,
+	novelID []byte,
+	chapterID []byte,
+	args *model.BookmarkMetadata,
+) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	uid := GetUUID()
+	_, err := db.db.ExecContext(
+		ctx,
+		`INSERT INTO bookmarks (id, user_id, novel_id, chapter_id, paragraph, note)
+		VALUES (?,?,?,?,?,?)`,
+		uid,
+		userID,
+		novelID,
+		chapterID,
+		args.Paragraph,
+		args.Note,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return []byte{}, false
+	}
+	return uid, true
+}
+
+func (db *Database) GetBookmark(bookmarkID []byte) (model.Bookmark, bool) {
+	var bookmark model.Bookmark
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &bookmark, "SELECT * FROM bookmarks WHERE id = ?", bookmarkID)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return bookmark, false
+	}
+	return bookmark, true
+}
+
+type bookmarkRaw struct {
+	model.Novel
+	BookmarkID   []byte    `db:"bookmark_id"`
+	ChapterID    []byte    `db:"chapter_id"`
+	ChapterTitle string    `db:"chapter_title"`
+	Paragraph    int       `db:"paragraph"`
+	Note         string    `db:"note"`
+	BookmarkAt   time.Time `db:"bookmark_at"`
+}
+
+// Return the bookmarks of the user grouped by novel, the novel bookmarked most
+// recently first and the bookmarks of each novel in reading order.
+// Deleted novels and the private novels of other authors are left out
+func (db *Database) GetBookmarks(userID []byte) []model.BookmarkGroup {
+	var groups []model.BookmarkGroup
+	var raws []bookmarkRaw
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&raws,
+		`SELECT novels.*,
+			bookmarks.id AS bookmark_id,
+			bookmarks.chapter_id,
+			chapters.title AS chapter_title,
+			bookmarks.paragraph,
+			bookmarks.note,
+			bookmarks.created_at AS bookmark_at
+		FROM bookmarks
+		INNER JOIN novels ON bookmarks.novel_id = novels.id
+		INNER JOIN chapters ON bookmarks.chapter_id = chapters.id
+		INNER JOIN volumes ON chapters.volume_id = volumes.id
+		WHERE bookmarks.user_id = ?
+			AND novels.deleted_at IS NULL
+			AND (novels.visibility = ? OR novels.author = ?)
+		ORDER BY
+			(SELECT MAX(latest.created_at) FROM bookmarks AS latest
+			WHERE latest.user_id = bookmarks.user_id
+			AND latest.novel_id = bookmarks.novel_id) DESC,
+			novels.id, volumes.position, chapters.position, bookmarks.paragraph`,
+		userID,
+		model.VisibilityPublic,
+		userID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return groups
+	}
+
+	for i := range raws {
+		raw := &raws[i]
+		last := len(groups) - 1
+		if last < 0 || !bytes.Equal(raws[i-1].ID, raw.ID) {
+			novel, ok := db.toNovelMetadataSmall(&raw.Novel)
+			if !ok {
+				return groups
+			}
+			groups = append(groups, model.BookmarkGroup{Novel: novel})
+			last++
+		}
+		groups[last].Bookmarks = append(groups[last].Bookmarks, model.BookmarkView{
+			ID:           hex.EncodeToString(raw.BookmarkID),
+			ChapterID:    hex.EncodeToString(raw.ChapterID),
+			ChapterTitle: raw.ChapterTitle,
+			Paragraph:    raw.Paragraph,
+			Note:         raw.Note,
+			CreateAt:     raw.BookmarkAt,
+		})
+	}
+	return groups
+}
+
+func (db *Database) DeleteBookmark(bookmarkID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(ctx, "DELETE FROM bookmarks WHERE id = ?", bookmarkID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Move the bookmarks on the chapter to the new index of their paragraph,
+// paragraphMap[i] is the new index of the paragraph previously at index i.
+// Nothing is moved if paragraphMap is nil
+func reanchorBookmarks(ctx context.Context, tx *sqlx.Tx, chapterID []byte, paragraphMap []int) error {
+	if paragraphMap == nil {
+		return nil
+	}
+
+	var bookmarks []model.Bookmark
+	err := tx.SelectContext(
+		ctx,
+		&bookmarks,
+		"SELECT * FROM bookmarks WHERE chapter_id = ?",
+		chapterID,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, bookmark := range bookmarks {
+		paragraph := 0
+		if len(paragraphMap) != 0 {
+			old := bookmark.Paragraph
+			if old >= len(paragraphMap) {
+				old = len(paragraphMap) - 1
+			}
+			paragraph = paragraphMap[old]
+		}
+		if paragraph == bookmark.Paragraph {
+			continue
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE bookmarks SET paragraph = ? WHERE id = ?",
+			paragraph,
+			bookmark.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+)
+
+func (db *Database) CreateChapter(volumeID []byte, args *model.ChapterMetadata) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	uid := GetUUID()
+	_, err := db.db.ExecContext(
+		ctx,
+		`INSERT INTO chapters
+		(id, volume_id, title, content, visibility, word_count, position)
+		SELECT ?,?,?,?,?,?, COALESCE(MAX(position), 0) + 1
+		FROM chapters
+		WHERE volume_id = ?`,
+		uid,
+		volumeID,
+		args.Title,
+		args.Content,
+		args.Visibility,
+		args.WordCount,
+		volumeID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return []byte{}, false
+	}
+	return uid, true
+}
+
+func (db *Database) GetChapter(chapterID []byte) (model.Chapter, bool) {
+	var chapter model.Chapter
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &chapter, "SELECT * FROM chapters WHERE id = ?", chapterID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return chapter, false
+	}
+	return chapter, true
+}
+
+// Return the IDs of the chapters in reading order of the whole novel,
+// private volumes and chapters are only included when isAuthor is true
+func (db *Database) getNovelChapterIDs(novelID []byte, isAuthor bool) [][]byte {
+	var chapterIDs [][]byte
+	query := `
+		SELECT chapters.id
+		FROM chapters
+		INNER JOIN volumes
+		ON chapters.volume_id = volumes.id
+		WHERE volumes.novel_id = ?`
+	if isAuthor == false {
+		query += fmt.Sprintf(
+			" AND volumes.visibility = %v AND chapters.visibility = %v",
+			int(model.VisibilityPublic),
+			int(model.VisibilityPublic),
+		)
+	}
+	query += `
+		ORDER BY volumes.position, volumes.created_at, volumes.id,
+			chapters.position, chapters.created_at, chapters.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(ctx, &chapterIDs, query, novelID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return [][]byte{}
+	}
+	return chapterIDs
+}
+
+// Return the hex encoded IDs of the previous and next chapters of the chapter,
+// the ID is an empty string if there is no such chapter
+func (db *Database) getAdjacentChapters(
+	novelID []byte,
+	chapterID []byte,
+	isAuthor bool,
+) (string, string) {
+	chapterIDs := db.getNovelChapterIDs(novelID, isAuthor)
+	prev, next := "", ""
+	for i, id := range chapterIDs {
+		if bytes.Equal(id, chapterID) {
+			if i > 0 {
+				prev = hex.EncodeToString(chapterIDs[i-1])
+			}
+			if i < len(chapterIDs)-1 {
+				next = hex.EncodeToString(chapterIDs[i+1])
+			}
+			break
+		}
+	}
+	return prev, next
+}
+
+func (db *Database) GetChapterView(
+	novelID []byte,
+	chapterID []byte,
+	isAuthor bool,
+) (model.ChapterView, bool) {
+	chapter, ok := db.GetChapter(chapterID)
+	if !ok {
+		return model.ChapterView{}, false
+	}
+
+	prev, next := db.getAdjacentChapters(novelID, chapterID, isAuthor)
+	return model.ChapterView{
+		ID:            hex.EncodeToString(chapter.ID),
+		VolumeID:      hex.EncodeToString(chapter.VolumeID),
+		Title:         chapter.Title,
+		Content:       chapter.Content,
+		CreateAt:      chapter.CreateAt,
+		UpdateAt:      chapter.UpdateAt,
+		Views:         chapter.Views,
+		Visibility:    chapter.Visibility.String(),
+		Position:      chapter.Position,
+		WordCount:     chapter.WordCount,
+		PrevChapterID: prev,
+		NextChapterID: next,
+	}, true
+}
+
+// Return the chapters of the volume without their content, private chapters
+// are only included when isAuthor is true
+func (db *Database) GetVolumeChapters(
+	volumeID []byte,
+	isAuthor bool,
+) []model.ChapterMetadataSmall {
+	var chapters []model.ChapterMetadataSmall
+	query := `
+		SELECT id, volume_id, title, created_at, updated_at,
+			views, visibility, position, word_count
+		FROM chapters
+		WHERE volume_id = ?`
+	if isAuthor == false {
+		query += fmt.Sprintf(" AND visibility = %v", int(model.VisibilityPublic))
+	}
+	query += " ORDER BY position, created_at, id"
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	row, err := db.db.QueryxContext(ctx, query, volumeID)
+	if err != nil {
+		cancel()
+		log.Error(err)
+		return chapters
+	}
+	defer func() {
+		err := row.Close()
+		if err != nil {
+			log.Error(err)
+		}
+		cancel()
+	}()
+	for row.Next() {
+		var chapter model.Chapter
+		err := row.StructScan(&chapter)
+		if err != nil {
+			log.Error(err)
+			return chapters
+		}
+		chapters = append(chapters, model.ChapterMetadataSmall{
+			ID:         hex.EncodeToString(chapter.ID),
+			VolumeID:   hex.EncodeToString(chapter.VolumeID),
+			Title:      chapter.Title,
+			CreateAt:   chapter.CreateAt,
+			UpdateAt:   chapter.UpdateAt,
+			Views:      chapter.Views,
+			Visibility: chapter.Visibility.String(),
+			Position:   chapter.Position,
+			WordCount:  chapter.WordCount,
+		})
+	}
+	return chapters
+}
+
+// Update the chapter and move the bookmarks on it to the new index of their
+// paragraph following args.ParagraphMap
+func (db *Database) UpdateChapter(chapterID []byte, args *model.ChapterMetadata) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`UPDATE chapters
+			SET title = ?, content = ?, visibility = ?, word_count = ?
+			WHERE id = ?`,
+			args.Title,
+			args.Content,
+			args.Visibility,
+			args.WordCount,
+			chapterID,
+		)
+		if err != nil {
+			return err
+		}
+
+		return reanchorBookmarks(ctx, tx, chapterID, args.ParagraphMap)
+	})
+}
+
+// Delete the chapter along with the comments on it, the open reports, the reading progress,
+// the bookmarks and the history entries on it, the resolved reports are kept as the record
+// of the moderation
+func (db *Database) DeleteChapter(chapterID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		return deleteChapter(ctx, tx, chapterID)
+	})
+}
+
+func deleteChapter(ctx context.Context, tx *sqlx.Tx, chapterID []byte) error {
+	commentIDs, err := getCommentTreeIDs(ctx, tx, [][]byte{chapterID})
+	if err != nil {
+		return err
+	}
+
+	deletions := []struct {
+		query string
+		ids   [][]byte
+	}{
+		{"DELETE FROM reports WHERE resolution_id IS NULL AND to_id IN (?)", append(commentIDs, chapterID)},
+		{"DELETE FROM comments WHERE id IN (?)", commentIDs},
+		{"DELETE FROM reading_progress WHERE chapter_id IN (?)", [][]byte{chapterID}},
+		{"DELETE FROM chapter_reads WHERE chapter_id IN (?)", [][]byte{chapterID}},
+		{"DELETE FROM bookmarks WHERE chapter_id IN (?)", [][]byte{chapterID}},
+		{"DELETE FROM reading_history WHERE chapter_id IN (?)", [][]byte{chapterID}},
+		{"DELETE FROM chapters WHERE id IN (?)", [][]byte{chapterID}},
+	}
+	for _, deletion := range deletions {
+		if err := execWithIDs(ctx, tx, deletion.query, deletion.ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewrite the position of the chapters of the volume to follow the order of chapterIDs,
+// the transaction is rolled back if chapterIDs is not exactly the chapters of the volume
+func (db *Database) ReorderChapters(volumeID []byte, chapterIDs [][]byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var currentIDs [][]byte
+		err := tx.SelectContext(
+			ctx,
+			&currentIDs,
+			"SELECT id FROM chapters WHERE volume_id = ? FOR UPDATE",
+			volumeID,
+		)
+		if err != nil {
+			return err
+		}
+		if !isSameIDSet(chapterIDs, currentIDs) {
+			return errReorderMismatch
+		}
+
+		for i, chapterID := range chapterIDs {
+			_, err := tx.ExecContext(
+				ctx,
+				"UPDATE chapters SET position = ? WHERE id = ? AND volume_id = ?",
+				i+1,
+				chapterID,
+				volumeID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,199 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+)
+
+func (db *Database) CreateComment(
+	toID []byte,
+	userID []byte,
+	args *model.CommentMetadata,
+) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	uid := GetUUID()
+	_, err := db.db.ExecContext(
+		ctx,
+		"INSERT INTO comments (id, to_id, user_id, content) VALUES (?,?,?,?)",
+		uid,
+		toID,
+		userID,
+		args.Content,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return []byte{}, false
+	}
+	return uid, true
+}
+
+func (db *Database) GetComment(commentID []byte) (model.Comment, bool) {
+	var comment model.Comment
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &comment, "SELECT * FROM comments WHERE id = ?", commentID)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return comment, false
+	}
+	return comment, true
+}
+
+// Follow the replies up to the novel, volume or chapter the thread is on and
+// return its ID, the target itself is returned if it is not a comment
+func (db *Database) GetCommentRootTarget(targetID []byte) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	for {
+		var toID []byte
+		err := db.db.GetContext(ctx, &toID, "SELECT to_id FROM comments WHERE id = ?", targetID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return targetID, true
+		}
+		if err != nil {
+			log.Error(err)
+			return nil, false
+		}
+		targetID = toID
+	}
+}
+
+type commentRaw struct {
+	model.Comment
+	ReplyCount int `db:"reply_count"`
+}
+
+// Return a page of the comments on the target from the newest to the oldest starting
+// after the cursor, the returned cursor is nil if there is no more comment. The comments
+// of the users the viewer blocked are left out, viewerID is nil for a guest
+func (db *Database) GetComments(
+	toID []byte,
+	viewerID []byte,
+	cursor *model.CommentCursor,
+) ([]model.CommentView, *model.CommentCursor) {
+	var comments []model.CommentView
+	replyFilter, commentFilter := "", ""
+	var args []interface{}
+	if viewerID != nil {
+		notBlocked := " AND %s.user_id NOT IN (SELECT to_id FROM blocks WHERE from_id = ?)"
+		replyFilter = fmt.Sprintf(notBlocked, "replies")
+		commentFilter = fmt.Sprintf(notBlocked, "comments")
+		args = append(args, viewerID)
+	}
+	query := `
+		SELECT comments.*,
+			(SELECT COUNT(*) FROM comments AS replies
+			WHERE replies.to_id = comments.id` + replyFilter + `)
+			AS reply_count
+		FROM comments
+		WHERE comments.to_id = ?` + commentFilter
+	args = append(args, toID)
+	if viewerID != nil {
+		args = append(args, viewerID)
+	}
+	if cursor != nil {
+		query += `
+		AND (comments.created_at < ? OR (comments.created_at = ? AND comments.id < ?))`
+		args = append(args, cursor.CreateAt, cursor.CreateAt, cursor.ID)
+	}
+	// Fetch one more comment to know if there is a next page
+	query += " ORDER BY comments.created_at DESC, comments.id DESC LIMIT ?"
+	args = append(args, model.PageSize+1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	row, err := db.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		log.Error(err)
+		return comments, nil
+	}
+	defer func() {
+		err := row.Close()
+		if err != nil {
+			log.Error(err)
+		}
+		cancel()
+	}()
+
+	var last commentRaw
+	for row.Next() {
+		if len(comments) == model.PageSize {
+			return comments, &model.CommentCursor{CreateAt: last.CreateAt, ID: last.ID}
+		}
+		var raw commentRaw
+		err := row.StructScan(&raw)
+		if err != nil {
+			log.Error(err)
+			return comments, nil
+		}
+		user, ok := db.GetUserMetadataSmall(raw.UserID)
+		if !ok {
+			return comments, nil
+		}
+		comments = append(comments, model.CommentView{
+			ID:         hex.EncodeToString(raw.ID),
+			ToID:       hex.EncodeToString(raw.ToID),
+			User:       user,
+			Content:    raw.Content,
+			ReplyCount: raw.ReplyCount,
+			CreateAt:   raw.CreateAt,
+			UpdateAt:   raw.UpdateAt,
+		})
+		last = raw
+	}
+	return comments, nil
+}
+
+func (db *Database) UpdateComment(commentID []byte, args *model.CommentMetadata) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE comments SET content = ? WHERE id = ?",
+		args.Content,
+		commentID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Delete the comment along with all of its replies and the open reports on them, the
+// resolved reports are kept as the record of the moderation
+func (db *Database) DeleteComment(commentID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		return deleteComment(ctx, tx, commentID)
+	})
+}
+
+func deleteComment(ctx context.Context, tx *sqlx.Tx, commentID []byte) error {
+	replyIDs, err := getCommentTreeIDs(ctx, tx, [][]byte{commentID})
+	if err != nil {
+		return err
+	}
+	commentIDs := append(replyIDs, commentID)
+
+	deletions := []string{
+		"DELETE FROM reports WHERE resolution_id IS NULL AND to_id IN (?)",
+		"DELETE FROM comments WHERE id IN (?)",
+	}
+	for _, query := range deletions {
+		if err := execWithIDs(ctx, tx, query, commentIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
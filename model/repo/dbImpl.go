@@ -19,6 +19,30 @@ func NewDatabase(db *sqlx.DB, timeoutDuration time.Duration) Database {
 	}
 }
 
+// Run fn inside a transaction, the transaction is committed if fn return nil
+// and rolled back otherwise
+func (db *Database) withTx(ctx context.Context, fn func(tx *sqlx.Tx) error) bool {
+	tx, err := db.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+
+	if err = fn(tx); err != nil {
+		log.Error(err)
+		if err := tx.Rollback(); err != nil {
+			log.Error(err)
+		}
+		return false
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
 func (db *Database) countUserFollowers(userID []byte) int {
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
 	follows := 0
@@ -53,6 +77,23 @@ func (db *Database) countUserFollows(fromID []byte) int {
 	return follows
 }
 
+func (db *Database) countNovelFollowers(novelID []byte) int {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	follows := 0
+	err := db.db.GetContext(
+		ctx,
+		&follows,
+		"SELECT COUNT(*) FROM follows_novel WHERE novel_id = ?",
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return 0
+	}
+	return follows
+}
+
 func (db *Database) countComments(toID []byte) int {
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
 	comments := 0
@@ -0,0 +1,177 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Make the user follow the other user, following twice has no effect
+func (db *Database) FollowUser(fromID []byte, toID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"INSERT IGNORE INTO follows_user (from_id, to_id) VALUES (?,?)",
+		fromID,
+		toID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) UnfollowUser(fromID []byte, toID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"DELETE FROM follows_user WHERE from_id = ? AND to_id = ?",
+		fromID,
+		toID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) IsFollowingUser(fromID []byte, toID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	following := false
+	err := db.db.GetContext(
+		ctx,
+		&following,
+		"SELECT EXISTS(SELECT 1 FROM follows_user WHERE from_id = ? AND to_id = ?)",
+		fromID,
+		toID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return following
+}
+
+func (db *Database) GetUserFollowers(userID []byte, page uint) []model.UserMetadataSmall {
+	return db.getFollowers(
+		`SELECT users.id, users.username, users.displayname, users.image
+		FROM follows_user INNER JOIN users
+		ON follows_user.from_id = users.id
+		WHERE follows_user.to_id = ?
+		ORDER BY users.username
+		LIMIT ? OFFSET ?`,
+		userID,
+		page,
+	)
+}
+
+// Make the user follow the novel, following twice has no effect
+func (db *Database) FollowNovel(userID []byte, novelID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"INSERT IGNORE INTO follows_novel (user_id, novel_id) VALUES (?,?)",
+		userID,
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) UnfollowNovel(userID []byte, novelID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"DELETE FROM follows_novel WHERE user_id = ? AND novel_id = ?",
+		userID,
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) IsFollowingNovel(userID []byte, novelID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	following := false
+	err := db.db.GetContext(
+		ctx,
+		&following,
+		"SELECT EXISTS(SELECT 1 FROM follows_novel WHERE user_id = ? AND novel_id = ?)",
+		userID,
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return following
+}
+
+func (db *Database) GetNovelFollowers(novelID []byte, page uint) []model.UserMetadataSmall {
+	return db.getFollowers(
+		`SELECT users.id, users.username, users.displayname, users.image
+		FROM follows_novel INNER JOIN users
+		ON follows_novel.user_id = users.id
+		WHERE follows_novel.novel_id = ?
+		ORDER BY users.username
+		LIMIT ? OFFSET ?`,
+		novelID,
+		page,
+	)
+}
+
+// Run the query which selects a page of users related to the target, like its followers
+func (db *Database) getFollowers(query string, targetID []byte, page uint) []model.UserMetadataSmall {
+	var users []model.UserMetadataSmall
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	row, err := db.db.QueryxContext(
+		ctx,
+		query,
+		targetID,
+		model.PageSize,
+		model.PageSize*(page-1),
+	)
+	if err != nil {
+		cancel()
+		log.Error(err)
+		return users
+	}
+	defer func() {
+		err := row.Close()
+		if err != nil {
+			log.Error(err)
+		}
+		cancel()
+	}()
+	for row.Next() {
+		var userMetaSmallRaw UserMetadatSmallRaw
+		err := row.StructScan(&userMetaSmallRaw)
+		if err != nil {
+			log.Error(err)
+			return users
+		}
+		users = append(users, model.UserMetadataSmall{
+			ID:          hex.EncodeToString(userMetaSmallRaw.Id),
+			Username:    userMetaSmallRaw.Username,
+			Displayname: userMetaSmallRaw.Displayname.String,
+			Image:       userMetaSmallRaw.Image,
+			Images:      model.NewImageVariants(userMetaSmallRaw.Image),
+		})
+	}
+	return users
+}
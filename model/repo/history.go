@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"time"
+)
+
+// Record that the user opened the chapter, nothing is recorded if the user
+// disabled their reading history
+func (db *Database) RecordHistory(userID []byte, novelID []byte, chapterID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		`INSERT INTO reading_history (id, user_id, novel_id, chapter_id)
+		SELECT ?, id, ?, ?
+		FROM users
+		WHERE id = ? AND history_enabled IS TRUE`,
+		GetUUID(),
+		novelID,
+		chapterID,
+		userID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+type historyEntryRaw struct {
+	model.HistoryEntry
+	NovelTitle   string `db:"novel_title"`
+	ChapterTitle string `db:"chapter_title"`
+}
+
+// Return a page of the reading history of the user, the newest first.
+// A zero from or to leaves that end of the range open
+func (db *Database) GetHistory(
+	userID []byte,
+	from time.Time,
+	to time.Time,
+	page uint,
+) []model.HistoryEntryView {
+	var entries []model.HistoryEntryView
+	var raws []historyEntryRaw
+	query := `
+		SELECT reading_history.*,
+			novels.title AS novel_title,
+			chapters.title AS chapter_title
+		FROM reading_history
+		INNER JOIN novels ON reading_history.novel_id = novels.id
+		INNER JOIN chapters ON reading_history.chapter_id = chapters.id
+		WHERE reading_history.user_id = ?`
+	args := []interface{}{userID}
+	if !from.IsZero() {
+		query += " AND reading_history.read_at >= ?"
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		query += " AND reading_history.read_at < ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY reading_history.read_at DESC, reading_history.id LIMIT ? OFFSET ?"
+	args = append(args, model.PageSize, model.PageSize*(page-1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(ctx, &raws, query, args...)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return entries
+	}
+
+	for _, raw := range raws {
+		entries = append(entries, model.HistoryEntryView{
+			ID:           hex.EncodeToString(raw.ID),
+			NovelID:      hex.EncodeToString(raw.NovelID),
+			NovelTitle:   raw.NovelTitle,
+			ChapterID:    hex.EncodeToString(raw.ChapterID),
+			ChapterTitle: raw.ChapterTitle,
+			ReadAt:       raw.ReadAt,
+		})
+	}
+	return entries
+}
+
+func (db *Database) GetHistoryEntry(entryID []byte) (model.HistoryEntry, bool) {
+	var entry model.HistoryEntry
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &entry, "SELECT * FROM reading_history WHERE id = ?", entryID)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return entry, false
+	}
+	return entry, true
+}
+
+func (db *Database) DeleteHistoryEntry(entryID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(ctx, "DELETE FROM reading_history WHERE id = ?", entryID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) ClearHistory(userID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(ctx, "DELETE FROM reading_history WHERE user_id = ?", userID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) SetHistoryEnabled(userID []byte, enabled bool) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE users SET history_enabled = ? WHERE id = ?",
+		enabled,
+		userID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
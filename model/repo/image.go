@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+)
+
+// Record the uploaded image, novelID is nil if the image is not for a novel
+func (db *Database) CreateImage(
+	userID []byte,
+	novelID []byte,
+	storageKey string,
+	contentType string,
+	size int,
+	url string,
+) (int, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	res, err := db.db.ExecContext(
+		ctx,
+		`INSERT INTO images
+		(user_id, novel_id, url, storage_key, content_type, size)
+		VALUES (?,?,?,?,?,?)`,
+		userID,
+		novelID,
+		url,
+		storageKey,
+		contentType,
+		size,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return 0, false
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		log.Error(err)
+		return 0, false
+	}
+	return int(id), true
+}
+
+func (db *Database) GetImageByKey(storageKey string) (model.Image, bool) {
+	var image model.Image
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &image, "SELECT * FROM images WHERE storage_key = ?", storageKey)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return image, false
+	}
+	return image, true
+}
+
+// Return the storage keys of the images uploaded by the user
+func (db *Database) GetUserImageKeys(userID []byte) []string {
+	var keys []string
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&keys,
+		"SELECT storage_key FROM images WHERE user_id = ? AND storage_key IS NOT NULL",
+		userID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+	}
+	return keys
+}
+
+// Return the uploaded image whose storage key is one of the keys
+func (db *Database) GetImageByKeys(storageKeys []string) (model.Image, bool) {
+	var image model.Image
+	query, args, err := sqlx.In("SELECT * FROM images WHERE storage_key IN (?) LIMIT 1", storageKeys)
+	if err != nil {
+		log.Error(err)
+		return image, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err = db.db.GetContext(ctx, &image, query, args...)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return image, false
+	}
+	return image, true
+}
+
+// Return the storage keys of the uploaded images which have no variants yet
+func (db *Database) GetPendingImageKeys() ([]string, bool) {
+	var keys []string
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&keys,
+		"SELECT storage_key FROM images WHERE variants_ready = FALSE AND storage_key IS NOT NULL",
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return keys, false
+	}
+	return keys, true
+}
+
+func (db *Database) SetImageVariantsReady(storageKey string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE images SET variants_ready = TRUE WHERE storage_key = ?",
+		storageKey,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
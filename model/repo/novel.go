@@ -3,31 +3,43 @@ package repo
 import (
 	"Lightnovel/model"
 	"context"
+	"database/sql"
 	"encoding/hex"
 	"fmt"
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+	"time"
 )
 
+// Create the novel along with its tags in the same transaction
 func (db *Database) CreateNovel(args *model.NovelMetadata) ([]byte, bool) {
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
 	uid := GetUUID()
-	_, err := db.db.ExecContext(
-		ctx,
-		`INSERT INTO novels 
-        (id, title, tagline, description, author, image, language, visibility) 
-		VALUES (?,?,?,?,?,?,?,?)`,
-		uid,
-		args.Title,
-		args.Tagline,
-		args.Description,
-		args.Author,
-		args.Image,
-		args.Language,
-		args.Visibility,
-	)
-	cancel()
-	if err != nil {
-		log.Error(err)
+	ok := db.withTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO novels 
+			(id, title, tagline, description, author, image, language, visibility) 
+			VALUES (?,?,?,?,?,?,?,?)`,
+			uid,
+			args.Title,
+			args.Tagline,
+			args.Description,
+			args.Author,
+			args.Image,
+			args.Language,
+			args.Visibility,
+		)
+		if err != nil {
+			return err
+		}
+		if err := promoteToAuthor(ctx, tx, args.Author); err != nil {
+			return err
+		}
+		return setNovelTags(ctx, tx, uid, args.Tags)
+	})
+	if !ok {
 		return []byte{}, false
 	}
 	return uid, true
@@ -36,7 +48,12 @@ func (db *Database) CreateNovel(args *model.NovelMetadata) ([]byte, bool) {
 func (db *Database) GetNovel(novelID []byte) (model.Novel, bool) {
 	var novel model.Novel
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
-	err := db.db.GetContext(ctx, &novel, "SELECT * FROM novels WHERE id = ?", novelID)
+	err := db.db.GetContext(
+		ctx,
+		&novel,
+		"SELECT * FROM novels WHERE id = ? AND deleted_at IS NULL",
+		novelID,
+	)
 	cancel()
 	if err != nil {
 		log.Error(err)
@@ -45,13 +62,37 @@ func (db *Database) GetNovel(novelID []byte) (model.Novel, bool) {
 	return novel, true
 }
 
+// Build the small view of the novel along with its author
+func (db *Database) toNovelMetadataSmall(novel *model.Novel) (model.NovelMetadataSmall, bool) {
+	authorMetadataSmall, ok := db.GetUserMetadataSmall(novel.Author)
+	if !ok {
+		return model.NovelMetadataSmall{}, false
+	}
+	return model.NovelMetadataSmall{
+		ID:          hex.EncodeToString(novel.ID),
+		Title:       novel.Title,
+		Tagline:     novel.Tagline,
+		Description: novel.Description,
+		Author:      authorMetadataSmall,
+		Image:       novel.Image,
+		Images:      model.NewImageVariants(novel.Image),
+		Language:    novel.Language,
+		TotalRating: novel.TotalRating,
+		RateCount:   novel.RateCount,
+		Adult:       novel.Adult,
+		Status:      novel.Status.String(),
+		Visibility:  novel.Visibility.String(),
+		Views:       novel.Views,
+	}, true
+}
+
 func (db *Database) getAuthor(authorID []byte) (model.UserView, bool) {
 	var user model.UserView
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
 	err := db.db.GetContext(
 		ctx,
 		&user,
-		`SELECT id, username, displayname, image, created_at 
+		`SELECT id, username, displayname, image, created_at, role
 		FROM users 
 		WHERE id = ?`,
 		authorID,
@@ -131,6 +172,7 @@ func (db *Database) GetNovelView(novelID []byte) (model.NovelView, bool) {
 		Tagline:     novel.Tagline,
 		Description: novel.Description,
 		Image:       novel.Image,
+		Images:      model.NewImageVariants(novel.Image),
 		Language:    novel.Language,
 		CreateAt:    novel.CreateAt,
 		UpdateAt:    novel.UpdateAt,
@@ -144,33 +186,36 @@ func (db *Database) GetNovelView(novelID []byte) (model.NovelView, bool) {
 		Visibility:  novel.Visibility.String(),
 		Tags:        db.getTags(novelID),
 		Volumes:     db.countVolume(novelID),
-		FollowCount: db.countUserFollowers(novelID),
+		FollowCount: db.countNovelFollowers(novelID),
 	}, true
 }
 
+// Update the novel metadata, the tags are replaced in the same transaction
+// unless they are omitted
 func (db *Database) UpdateNovelMetadata(novelID []byte, args *model.NovelMetadata) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
-	_, err := db.db.ExecContext(
-		ctx,
-		`UPDATE novels 
-		SET title = ?, tagline = ?, description = ?, image = ?, 
-		    language = ?, visibility = ?, status = ? 
-		WHERE id = ?`,
-		args.Title,
-		args.Tagline,
-		args.Description,
-		args.Image,
-		args.Language,
-		args.Visibility,
-		args.Status,
-		novelID,
-	)
-	cancel()
-	if err != nil {
-		log.Error(err)
-		return false
-	}
-	return true
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`UPDATE novels 
+			SET title = ?, tagline = ?, description = ?, image = ?, 
+				language = ?, visibility = ?, status = ? 
+			WHERE id = ?`,
+			args.Title,
+			args.Tagline,
+			args.Description,
+			args.Image,
+			args.Language,
+			args.Visibility,
+			args.Status,
+			novelID,
+		)
+		if err != nil || args.Tags == nil {
+			return err
+		}
+		return setNovelTags(ctx, tx, novelID, args.Tags)
+	})
 }
 
 func (db *Database) GetUsersNovels(
@@ -193,7 +238,7 @@ func (db *Database) GetUsersNovels(
 		) AS TABLE1
 		ON TABLE1.novel_id = novels.id`
 	}
-	query += ` WHERE novels.author = ?`
+	query += ` WHERE novels.author = ? AND novels.deleted_at IS NULL`
 	if isSelf == false {
 		query += fmt.Sprintf(" AND novels.visibility = %v", int(model.VisibilityPublic))
 	}
@@ -228,25 +273,11 @@ func (db *Database) GetUsersNovels(
 			log.Error(err)
 			return novels
 		}
-		authorMetadataSmall, ok := db.GetUserMetadataSmall(novel.Author)
+		novelMetadataSmall, ok := db.toNovelMetadataSmall(&novel)
 		if !ok {
 			return novels
 		}
-		novels = append(novels, model.NovelMetadataSmall{
-			ID:          hex.EncodeToString(novel.ID),
-			Title:       novel.Title,
-			Tagline:     novel.Tagline,
-			Description: novel.Description,
-			Author:      authorMetadataSmall,
-			Image:       novel.Image,
-			Language:    novel.Language,
-			TotalRating: novel.TotalRating,
-			RateCount:   novel.RateCount,
-			Adult:       novel.Adult,
-			Status:      novel.Status.String(),
-			Visibility:  novel.Visibility.String(),
-			Views:       novel.Views,
-		})
+		novels = append(novels, novelMetadataSmall)
 	}
 	return novels
 }
@@ -269,7 +300,7 @@ func (db *Database) FindNovels(
 		) AS TABLE1
 		ON TABLE1.novel_id = novels.id`
 	}
-	query += " WHERE 1=1 "
+	query += " WHERE novels.deleted_at IS NULL "
 	query += filtersAndSortQuery
 
 	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
@@ -296,25 +327,274 @@ func (db *Database) FindNovels(
 			log.Error(err)
 			return novels
 		}
-		authorMetadataSmall, ok := db.GetUserMetadataSmall(novel.Author)
+		novelMetadataSmall, ok := db.toNovelMetadataSmall(&novel)
 		if !ok {
 			return novels
 		}
-		novels = append(novels, model.NovelMetadataSmall{
-			ID:          hex.EncodeToString(novel.ID),
-			Title:       novel.Title,
-			Tagline:     novel.Tagline,
-			Description: novel.Description,
-			Author:      authorMetadataSmall,
-			Image:       novel.Image,
-			Language:    novel.Language,
-			TotalRating: novel.TotalRating,
-			RateCount:   novel.RateCount,
-			Adult:       novel.Adult,
-			Status:      novel.Status.String(),
-			Visibility:  novel.Visibility.String(),
-			Views:       novel.Views,
-		})
+		novels = append(novels, novelMetadataSmall)
 	}
 	return novels
 }
+
+type tocRowRaw struct {
+	VolumeID          []byte         `db:"volume_id"`
+	VolumeTitle       string         `db:"volume_title"`
+	VolumePosition    int            `db:"volume_position"`
+	VolumeUpdateAt    time.Time      `db:"volume_updated_at"`
+	VolumeVisibility  int            `db:"volume_visibility"`
+	ChapterID         []byte         `db:"chapter_id"`
+	ChapterTitle      sql.NullString `db:"chapter_title"`
+	ChapterPosition   sql.NullInt64  `db:"chapter_position"`
+	ChapterUpdateAt   sql.NullTime   `db:"chapter_updated_at"`
+	ChapterWordCount  sql.NullInt64  `db:"chapter_word_count"`
+	ChapterVisibility sql.NullInt64  `db:"chapter_visibility"`
+}
+
+// Return the volumes of the novel along with their chapters in reading order,
+// private volumes and chapters are only included when isAuthor is true
+func (db *Database) GetNovelTOC(novelID []byte, isAuthor bool) (model.NovelTOC, bool) {
+	toc := model.NovelTOC{
+		NovelID: hex.EncodeToString(novelID),
+		Volumes: []model.VolumeTOC{},
+	}
+	query := `
+		SELECT
+			volumes.id AS volume_id,
+			volumes.title AS volume_title,
+			volumes.position AS volume_position,
+			volumes.updated_at AS volume_updated_at,
+			volumes.visibility AS volume_visibility,
+			chapters.id AS chapter_id,
+			chapters.title AS chapter_title,
+			chapters.position AS chapter_position,
+			chapters.updated_at AS chapter_updated_at,
+			chapters.word_count AS chapter_word_count,
+			chapters.visibility AS chapter_visibility
+		FROM volumes
+		LEFT JOIN chapters
+		ON chapters.volume_id = volumes.id`
+	if isAuthor == false {
+		query += fmt.Sprintf(" AND chapters.visibility = %v", int(model.VisibilityPublic))
+	}
+	query += " WHERE volumes.novel_id = ?"
+	if isAuthor == false {
+		query += fmt.Sprintf(" AND volumes.visibility = %v", int(model.VisibilityPublic))
+	}
+	query += `
+		ORDER BY volumes.position, volumes.created_at, volumes.id,
+			chapters.position, chapters.created_at, chapters.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	row, err := db.db.QueryxContext(ctx, query, novelID)
+	if err != nil {
+		cancel()
+		log.Error(err)
+		return toc, false
+	}
+	defer func() {
+		err := row.Close()
+		if err != nil {
+			log.Error(err)
+		}
+		cancel()
+	}()
+	for row.Next() {
+		var raw tocRowRaw
+		err := row.StructScan(&raw)
+		if err != nil {
+			log.Error(err)
+			return toc, false
+		}
+
+		volumeID := hex.EncodeToString(raw.VolumeID)
+		last := len(toc.Volumes) - 1
+		if last < 0 || toc.Volumes[last].ID != volumeID {
+			toc.Volumes = append(toc.Volumes, model.VolumeTOC{
+				ID:         volumeID,
+				Title:      raw.VolumeTitle,
+				Position:   raw.VolumePosition,
+				UpdateAt:   raw.VolumeUpdateAt,
+				Visibility: model.VisibilityID(raw.VolumeVisibility).String(),
+				Chapters:   []model.ChapterTOC{},
+			})
+			last++
+		}
+
+		// Volume without any chapter
+		if raw.ChapterID == nil {
+			continue
+		}
+		toc.Volumes[last].Chapters = append(toc.Volumes[last].Chapters, model.ChapterTOC{
+			ID:         hex.EncodeToString(raw.ChapterID),
+			Title:      raw.ChapterTitle.String,
+			Position:   int(raw.ChapterPosition.Int64),
+			UpdateAt:   raw.ChapterUpdateAt.Time,
+			WordCount:  int(raw.ChapterWordCount.Int64),
+			Visibility: model.VisibilityID(raw.ChapterVisibility.Int64).String(),
+		})
+	}
+	return toc, true
+}
+
+// Soft deleted novels can be restored within this duration, after that they are
+// purged along with all of their related data
+var novelRestoreDuration = time.Hour * 24 * 7
+
+// Soft delete the novel, the novel is hidden everywhere until it is restored
+func (db *Database) DeleteNovel(novelID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE novels SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		time.Now(),
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Soft delete the novel on behalf of a moderator, the author cannot restore it
+// and it is purged with the other deleted novels
+func takeDownNovel(ctx context.Context, tx *sqlx.Tx, novelID []byte) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE novels
+		SET deleted_at = IFNULL(deleted_at, CURRENT_TIMESTAMP), taken_down_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		novelID,
+	)
+	return err
+}
+
+// Return the novel if it is soft deleted and still can be restored
+func (db *Database) GetDeletedNovel(novelID []byte) (model.Novel, bool) {
+	var novel model.Novel
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(
+		ctx,
+		&novel,
+		"SELECT * FROM novels WHERE id = ? AND deleted_at > ? AND taken_down_at IS NULL",
+		novelID,
+		time.Now().Add(-novelRestoreDuration),
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return novel, false
+	}
+	return novel, true
+}
+
+func (db *Database) RestoreNovel(novelID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE novels SET deleted_at = NULL WHERE id = ? AND deleted_at > ? AND taken_down_at IS NULL",
+		novelID,
+		time.Now().Add(-novelRestoreDuration),
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Permanently delete the novels which are soft deleted longer than the restore duration
+func (db *Database) PurgeDeletedNovels() bool {
+	var novelIDs [][]byte
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&novelIDs,
+		"SELECT id FROM novels WHERE deleted_at <= ?",
+		time.Now().Add(-novelRestoreDuration),
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+
+	res := true
+	for _, novelID := range novelIDs {
+		ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+		ok := db.withTx(ctx, func(tx *sqlx.Tx) error {
+			return purgeNovel(ctx, tx, novelID)
+		})
+		cancel()
+		res = res && ok
+	}
+	return res
+}
+
+// Delete the novel and everything related to it: volumes, chapters, comments
+// and their replies, reports, tags and follows, the uploaded images are kept by
+// their uploader
+func purgeNovel(ctx context.Context, tx *sqlx.Tx, novelID []byte) error {
+	var volumeIDs, chapterIDs [][]byte
+	err := tx.SelectContext(ctx, &volumeIDs, "SELECT id FROM volumes WHERE novel_id = ?", novelID)
+	if err != nil {
+		return err
+	}
+	err = tx.SelectContext(
+		ctx,
+		&chapterIDs,
+		`SELECT chapters.id
+		FROM chapters INNER JOIN volumes
+		ON chapters.volume_id = volumes.id
+		WHERE volumes.novel_id = ?`,
+		novelID,
+	)
+	if err != nil {
+		return err
+	}
+
+	var reviewIDs [][]byte
+	err = tx.SelectContext(ctx, &reviewIDs, "SELECT id FROM reviews WHERE novel_id = ?", novelID)
+	if err != nil {
+		return err
+	}
+	if err := deleteReviews(ctx, tx, reviewIDs); err != nil {
+		return err
+	}
+
+	targetIDs := append([][]byte{novelID}, volumeIDs...)
+	targetIDs = append(targetIDs, chapterIDs...)
+	commentIDs, err := getCommentTreeIDs(ctx, tx, targetIDs)
+	if err != nil {
+		return err
+	}
+
+	reportedIDs := append(targetIDs, commentIDs...)
+	deletions := []struct {
+		query string
+		ids   [][]byte
+	}{
+		{"DELETE FROM reports WHERE to_id IN (?)", reportedIDs},
+		{"DELETE FROM comments WHERE id IN (?)", commentIDs},
+		{"DELETE FROM chapters WHERE id IN (?)", chapterIDs},
+		{"DELETE FROM volumes WHERE id IN (?)", volumeIDs},
+		{"DELETE FROM novel_tags WHERE novel_id IN (?)", [][]byte{novelID}},
+		{"UPDATE images SET novel_id = NULL WHERE novel_id IN (?)", [][]byte{novelID}},
+		{"DELETE FROM follows_novel WHERE novel_id IN (?)", [][]byte{novelID}},
+		{"DELETE FROM shelf_novels WHERE novel_id IN (?)", [][]byte{novelID}},
+		{"DELETE FROM ratings WHERE novel_id IN (?)", [][]byte{novelID}},
+		{"DELETE FROM reading_progress WHERE novel_id IN (?)", [][]byte{novelID}},
+		{"DELETE FROM chapter_reads WHERE novel_id IN (?)", [][]byte{novelID}},
+		{"DELETE FROM bookmarks WHERE novel_id IN (?)", [][]byte{novelID}},
+		{"DELETE FROM reading_history WHERE novel_id IN (?)", [][]byte{novelID}},
+		{"DELETE FROM novels WHERE id IN (?)", [][]byte{novelID}},
+	}
+	for _, deletion := range deletions {
+		if err := execWithIDs(ctx, tx, deletion.query, deletion.ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
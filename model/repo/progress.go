@@ -0,0 +1,182 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"time"
+)
+
+// Record the chapter and the scroll offset the user is at in the novel,
+// replacing the previous progress on the novel
+func (db *Database) SaveReadingProgress(
+	userID []byte,
+	novelID []byte,
+	chapterID []byte,
+	args *model.ProgressMetadata,
+) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		`INSERT INTO reading_progress (user_id, novel_id, chapter_id, scroll_offset)
+		VALUES (?,?,?,?)
+		ON DUPLICATE KEY UPDATE
+			chapter_id = VALUES(chapter_id),
+			scroll_offset = VALUES(scroll_offset),
+			updated_at = CURRENT_TIMESTAMP`,
+		userID,
+		novelID,
+		chapterID,
+		args.ScrollOffset,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Return the progress of the user on the novel, false if the user has not started it
+func (db *Database) GetReadingProgress(userID []byte, novelID []byte) (model.ReadingProgress, bool) {
+	var progress model.ReadingProgress
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(
+		ctx,
+		&progress,
+		"SELECT * FROM reading_progress WHERE user_id = ? AND novel_id = ?",
+		userID,
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return progress, false
+	}
+	return progress, true
+}
+
+type readingProgressRaw struct {
+	model.Novel
+	ChapterID        []byte    `db:"chapter_id"`
+	ChapterTitle     string    `db:"chapter_title"`
+	ScrollOffset     float64   `db:"scroll_offset"`
+	ReadChapterCount int       `db:"read_chapter_count"`
+	ProgressAt       time.Time `db:"progress_at"`
+}
+
+// Return a page of the novels the user is reading, the most recently read first.
+// Deleted novels and the private novels of other authors are left out
+func (db *Database) GetContinueReading(userID []byte, page uint) []model.ReadingProgressView {
+	var progresses []model.ReadingProgressView
+	var raws []readingProgressRaw
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&raws,
+		`SELECT novels.*,
+			reading_progress.chapter_id,
+			chapters.title AS chapter_title,
+			reading_progress.scroll_offset,
+			reading_progress.updated_at AS progress_at,
+			(SELECT COUNT(*) FROM chapter_reads
+			WHERE chapter_reads.user_id = reading_progress.user_id
+			AND chapter_reads.novel_id = novels.id) AS read_chapter_count
+		FROM reading_progress
+		INNER JOIN novels ON reading_progress.novel_id = novels.id
+		INNER JOIN chapters ON reading_progress.chapter_id = chapters.id
+		WHERE reading_progress.user_id = ?
+			AND novels.deleted_at IS NULL
+			AND (novels.visibility = ? OR novels.author = ?)
+		ORDER BY reading_progress.updated_at DESC
+		LIMIT ? OFFSET ?`,
+		userID,
+		model.VisibilityPublic,
+		userID,
+		model.PageSize,
+		model.PageSize*(page-1),
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return progresses
+	}
+
+	for _, raw := range raws {
+		novel, ok := db.toNovelMetadataSmall(&raw.Novel)
+		if !ok {
+			return progresses
+		}
+		progresses = append(progresses, model.ReadingProgressView{
+			Novel:            novel,
+			ChapterID:        hex.EncodeToString(raw.ChapterID),
+			ChapterTitle:     raw.ChapterTitle,
+			ScrollOffset:     raw.ScrollOffset,
+			ReadChapterCount: raw.ReadChapterCount,
+			UpdateAt:         raw.ProgressAt,
+		})
+	}
+	return progresses
+}
+
+// Mark the chapter of the novel as read by the user, marking twice has no effect
+func (db *Database) MarkChapterRead(userID []byte, novelID []byte, chapterID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"INSERT IGNORE INTO chapter_reads (user_id, chapter_id, novel_id) VALUES (?,?,?)",
+		userID,
+		chapterID,
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) UnmarkChapterRead(userID []byte, chapterID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"DELETE FROM chapter_reads WHERE user_id = ? AND chapter_id = ?",
+		userID,
+		chapterID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Return the IDs of the chapters of the novel the user marked as read
+func (db *Database) GetReadChapterIDs(userID []byte, novelID []byte) []string {
+	var chapterIDs [][]byte
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&chapterIDs,
+		"SELECT chapter_id FROM chapter_reads WHERE user_id = ? AND novel_id = ?",
+		userID,
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+	}
+
+	ids := make([]string, len(chapterIDs))
+	for i, chapterID := range chapterIDs {
+		ids[i] = hex.EncodeToString(chapterID)
+	}
+	return ids
+}
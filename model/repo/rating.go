@@ -0,0 +1,169 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+)
+
+// Create or change the user's rating of the novel, the total rating and
+// the rate count of the novel are updated in the same transaction
+func (db *Database) RateNovel(userID []byte, novelID []byte, rating int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		return setRating(ctx, tx, userID, novelID, rating)
+	})
+}
+
+// Remove the user's rating of the novel along with the user's review, the total
+// rating and the rate count of the novel are updated in the same transaction
+func (db *Database) DeleteRating(userID []byte, novelID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var reviewIDs [][]byte
+		err := tx.SelectContext(
+			ctx,
+			&reviewIDs,
+			"SELECT id FROM reviews WHERE user_id = ? AND novel_id = ?",
+			userID,
+			novelID,
+		)
+		if err != nil {
+			return err
+		}
+		if err := deleteReviews(ctx, tx, reviewIDs); err != nil {
+			return err
+		}
+		return removeRating(ctx, tx, userID, novelID)
+	})
+}
+
+// Return the user's rating of the novel, 0 if the user has not rated the novel
+func (db *Database) GetUserRating(userID []byte, novelID []byte) int {
+	rating := 0
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(
+		ctx,
+		&rating,
+		"SELECT rating FROM ratings WHERE user_id = ? AND novel_id = ?",
+		userID,
+		novelID,
+	)
+	cancel()
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Error(err)
+	}
+	return rating
+}
+
+func setRating(ctx context.Context, tx *sqlx.Tx, userID []byte, novelID []byte, rating int) error {
+	oldRating := 0
+	err := tx.GetContext(
+		ctx,
+		&oldRating,
+		"SELECT rating FROM ratings WHERE user_id = ? AND novel_id = ? FOR UPDATE",
+		userID,
+		novelID,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO ratings (user_id, novel_id, rating) VALUES (?,?,?)",
+			userID,
+			novelID,
+			rating,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE novels
+			SET total_rating = total_rating + ?, rate_count = rate_count + 1
+			WHERE id = ?`,
+			rating,
+			novelID,
+		)
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE ratings SET rating = ? WHERE user_id = ? AND novel_id = ?",
+		rating,
+		userID,
+		novelID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE novels SET total_rating = total_rating + ? WHERE id = ?",
+		rating-oldRating,
+		novelID,
+	)
+	return err
+}
+
+func removeRating(ctx context.Context, tx *sqlx.Tx, userID []byte, novelID []byte) error {
+	oldRating := 0
+	err := tx.GetContext(
+		ctx,
+		&oldRating,
+		"SELECT rating FROM ratings WHERE user_id = ? AND novel_id = ? FOR UPDATE",
+		userID,
+		novelID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"DELETE FROM ratings WHERE user_id = ? AND novel_id = ?",
+		userID,
+		novelID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE novels
+		SET total_rating = total_rating - ?, rate_count = rate_count - 1
+		WHERE id = ?`,
+		oldRating,
+		novelID,
+	)
+	return err
+}
+
+// Remove every rating of the user and take them out of the novels' aggregates
+func deleteUserRatings(ctx context.Context, tx *sqlx.Tx, userID []byte) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE novels
+		INNER JOIN ratings
+		ON ratings.novel_id = novels.id AND ratings.user_id = ?
+		SET novels.total_rating = novels.total_rating - ratings.rating,
+			novels.rate_count = novels.rate_count - 1`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM ratings WHERE user_id = ?", userID)
+	return err
+}
@@ -0,0 +1,309 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+func (db *Database) GetReportReasons() []model.ReportReason {
+	var reasons []model.ReportReason
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(ctx, &reasons, "SELECT * FROM report_reason ORDER BY id")
+	cancel()
+	if err != nil {
+		log.Error(err)
+	}
+	return reasons
+}
+
+// Check if the user has a report on the target which is not resolved yet
+func (db *Database) HasOpenReport(userID []byte, toID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	exists := false
+	err := db.db.GetContext(
+		ctx,
+		&exists,
+		`SELECT EXISTS(SELECT 1 FROM reports
+		WHERE user_id = ? AND to_id = ? AND resolution_id IS NULL)`,
+		userID,
+		toID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return exists
+}
+
+func (db *Database) CreateReport(
+	userID []byte,
+	toID []byte,
+	targetType model.ReportTarget,
+	args *model.ReportMetadata,
+) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		`INSERT INTO reports (user_id, to_id, target_type, reason_id, details)
+		VALUES (?,?,?,?,?)`,
+		userID,
+		toID,
+		targetType,
+		args.ReasonID,
+		args.Details,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+type reportGroupRaw struct {
+	ToID          []byte             `db:"to_id"`
+	TargetType    model.ReportTarget `db:"target_type"`
+	ReportCount   int                `db:"report_count"`
+	FirstReportAt time.Time          `db:"first_report_at"`
+	LastReportAt  time.Time          `db:"last_report_at"`
+}
+
+const reportGroupQuery = `
+	SELECT to_id, target_type, COUNT(*) AS report_count,
+		MIN(created_at) AS first_report_at, MAX(created_at) AS last_report_at
+	FROM reports
+	WHERE resolution_id IS NULL`
+
+// Return a page of the reported targets with open reports, the most reported first
+func (db *Database) GetReportQueue(page uint) []model.ReportGroup {
+	var groupsRaw []reportGroupRaw
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	err := db.db.SelectContext(
+		ctx,
+		&groupsRaw,
+		reportGroupQuery+`
+		GROUP BY to_id, target_type
+		ORDER BY report_count DESC, first_report_at
+		LIMIT ? OFFSET ?`,
+		model.PageSize,
+		model.PageSize*(page-1),
+	)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	groups, ok := db.toReportGroups(ctx, groupsRaw)
+	if !ok {
+		return nil
+	}
+	return groups
+}
+
+// Return the open reports on the target, false if there is none
+func (db *Database) GetReportGroup(toID []byte) (model.ReportGroup, bool) {
+	var groupsRaw []reportGroupRaw
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	err := db.db.SelectContext(
+		ctx,
+		&groupsRaw,
+		reportGroupQuery+" AND to_id = ? GROUP BY to_id, target_type",
+		toID,
+	)
+	if err != nil {
+		log.Error(err)
+		return model.ReportGroup{}, false
+	}
+	groups, ok := db.toReportGroups(ctx, groupsRaw)
+	if !ok || len(groups) == 0 {
+		return model.ReportGroup{}, false
+	}
+	return groups[0], true
+}
+
+// Add the reason counts and the details of the open reports to the groups
+func (db *Database) toReportGroups(ctx context.Context, groupsRaw []reportGroupRaw) ([]model.ReportGroup, bool) {
+	if len(groupsRaw) == 0 {
+		return nil, true
+	}
+	toIDs := make([][]byte, len(groupsRaw))
+	for i, groupRaw := range groupsRaw {
+		toIDs[i] = groupRaw.ToID
+	}
+
+	var reasons []struct {
+		ToID []byte `db:"to_id"`
+		model.ReportReasonCount
+	}
+	query, args, err := sqlx.In(
+		`SELECT reports.to_id, reports.reason_id, report_reason.reason, COUNT(*) AS count
+		FROM reports INNER JOIN report_reason
+		ON reports.reason_id = report_reason.id
+		WHERE reports.resolution_id IS NULL AND reports.to_id IN (?)
+		GROUP BY reports.to_id, reports.reason_id, report_reason.reason
+		ORDER BY count DESC`,
+		toIDs,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil, false
+	}
+	if err := db.db.SelectContext(ctx, &reasons, db.db.Rebind(query), args...); err != nil {
+		log.Error(err)
+		return nil, false
+	}
+
+	var details []struct {
+		ToID    []byte `db:"to_id"`
+		Details string `db:"details"`
+	}
+	query, args, err = sqlx.In(
+		`SELECT to_id, details FROM reports
+		WHERE resolution_id IS NULL AND details != '' AND to_id IN (?)
+		ORDER BY created_at`,
+		toIDs,
+	)
+	if err != nil {
+		log.Error(err)
+		return nil, false
+	}
+	if err := db.db.SelectContext(ctx, &details, db.db.Rebind(query), args...); err != nil {
+		log.Error(err)
+		return nil, false
+	}
+
+	groups := make([]model.ReportGroup, len(groupsRaw))
+	indexes := make(map[string]int, len(groupsRaw))
+	for i, groupRaw := range groupsRaw {
+		groups[i] = model.ReportGroup{
+			TargetID:      hex.EncodeToString(groupRaw.ToID),
+			TargetType:    groupRaw.TargetType,
+			ReportCount:   groupRaw.ReportCount,
+			Reasons:       []model.ReportReasonCount{},
+			Details:       []string{},
+			FirstReportAt: groupRaw.FirstReportAt,
+			LastReportAt:  groupRaw.LastReportAt,
+		}
+		indexes[string(groupRaw.ToID)] = i
+	}
+	for _, reason := range reasons {
+		group := &groups[indexes[string(reason.ToID)]]
+		group.Reasons = append(group.Reasons, reason.ReportReasonCount)
+	}
+	for _, detail := range details {
+		group := &groups[indexes[string(detail.ToID)]]
+		group.Details = append(group.Details, detail.Details)
+	}
+	return groups, true
+}
+
+// Close every open report on the target with the moderator's decision, taking action
+// removes the target or bans the user in the same transaction. The open reports are
+// locked so they are only resolved once, found is false if there is none left
+func (db *Database) ResolveReports(
+	toID []byte,
+	moderatorID []byte,
+	action model.ReportAction,
+	outcome string,
+) (found bool, ok bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	ok = db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var targetTypes []model.ReportTarget
+		err := tx.SelectContext(
+			ctx,
+			&targetTypes,
+			"SELECT target_type FROM reports WHERE to_id = ? AND resolution_id IS NULL FOR UPDATE",
+			toID,
+		)
+		if err != nil {
+			return err
+		}
+		if len(targetTypes) == 0 {
+			return nil
+		}
+		found = true
+		targetType := targetTypes[0]
+
+		res, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO report_resolutions
+			(to_id, target_type, moderator_id, action, outcome, report_count)
+			VALUES (?,?,?,?,?,?)`,
+			toID,
+			targetType,
+			moderatorID,
+			action,
+			outcome,
+			len(targetTypes),
+		)
+		if err != nil {
+			return err
+		}
+		resolutionID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE reports SET resolution_id = ? WHERE to_id = ? AND resolution_id IS NULL",
+			resolutionID,
+			toID,
+		)
+		if err != nil || action != model.ReportActionTakeAction {
+			return err
+		}
+
+		// The reports stay open if the target cannot be removed
+		switch targetType {
+		case model.ReportTargetNovel:
+			return takeDownNovel(ctx, tx, toID)
+		case model.ReportTargetChapter:
+			return deleteChapter(ctx, tx, toID)
+		case model.ReportTargetComment:
+			return deleteComment(ctx, tx, toID)
+		case model.ReportTargetUser:
+			return banUser(ctx, tx, toID)
+		}
+		return nil
+	})
+	return found && ok, ok
+}
+
+// Return the recorded outcomes of the reports on the target from the newest to the oldest
+func (db *Database) GetReportResolutions(toID []byte) []model.ReportResolutionView {
+	var resolutions []model.ReportResolution
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&resolutions,
+		"SELECT * FROM report_resolutions WHERE to_id = ? ORDER BY created_at DESC, id DESC",
+		toID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+	}
+
+	views := make([]model.ReportResolutionView, len(resolutions))
+	for i, resolution := range resolutions {
+		views[i] = model.ReportResolutionView{
+			ID:          resolution.ID,
+			TargetID:    hex.EncodeToString(resolution.ToID),
+			TargetType:  resolution.TargetType,
+			ModeratorID: hex.EncodeToString(resolution.ModeratorID),
+			Action:      resolution.Action,
+			Outcome:     resolution.Outcome,
+			ReportCount: resolution.ReportCount,
+			CreateAt:    resolution.CreatedAt,
+		}
+	}
+	return views
+}
@@ -0,0 +1,310 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// Create the user's review of the novel, the rating in the review is saved
+// as the user's rating of the novel in the same transaction
+func (db *Database) CreateReview(
+	userID []byte,
+	novelID []byte,
+	args *model.ReviewMetadata,
+) ([]byte, bool) {
+	uid := GetUUID()
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	ok := db.withTx(ctx, func(tx *sqlx.Tx) error {
+		if err := setRating(ctx, tx, userID, novelID, args.Rating); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO reviews
+			(id, user_id, novel_id, title, content, spoiler)
+			VALUES (?,?,?,?,?,?)`,
+			uid,
+			userID,
+			novelID,
+			args.Title,
+			args.Content,
+			args.Spoiler,
+		)
+		return err
+	})
+	if !ok {
+		return []byte{}, false
+	}
+	return uid, true
+}
+
+func (db *Database) GetReview(reviewID []byte) (model.Review, bool) {
+	var review model.Review
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &review, "SELECT * FROM reviews WHERE id = ?", reviewID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return review, false
+	}
+	return review, true
+}
+
+func (db *Database) GetUserReview(userID []byte, novelID []byte) (model.Review, bool) {
+	var review model.Review
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(
+		ctx,
+		&review,
+		"SELECT * FROM reviews WHERE user_id = ? AND novel_id = ?",
+		userID,
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		return review, false
+	}
+	return review, true
+}
+
+type reviewRaw struct {
+	model.Review
+	Rating int
+}
+
+func (db *Database) GetNovelReviews(
+	novelID []byte,
+	sort model.ReviewSort,
+	page uint,
+) []model.ReviewView {
+	var reviews []model.ReviewView
+	query := `
+		SELECT reviews.*, COALESCE(ratings.rating, 0) AS rating
+		FROM reviews
+		LEFT JOIN ratings
+		ON ratings.user_id = reviews.user_id AND ratings.novel_id = reviews.novel_id
+		WHERE reviews.novel_id = ?`
+	if sort == model.ReviewSortHelpful {
+		query += " ORDER BY reviews.helpful_count DESC, reviews.created_at DESC, reviews.id"
+	} else {
+		query += " ORDER BY reviews.created_at DESC, reviews.id"
+	}
+	query += " LIMIT ? OFFSET ?"
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	row, err := db.db.QueryxContext(
+		ctx,
+		query,
+		novelID,
+		model.PageSize,
+		model.PageSize*(page-1),
+	)
+	if err != nil {
+		cancel()
+		log.Error(err)
+		return reviews
+	}
+	defer func() {
+		err := row.Close()
+		if err != nil {
+			log.Error(err)
+		}
+		cancel()
+	}()
+	for row.Next() {
+		var raw reviewRaw
+		err := row.StructScan(&raw)
+		if err != nil {
+			log.Error(err)
+			return reviews
+		}
+		user, ok := db.GetUserMetadataSmall(raw.UserID)
+		if !ok {
+			return reviews
+		}
+		reviews = append(reviews, model.ReviewView{
+			ID:           hex.EncodeToString(raw.ID),
+			NovelID:      hex.EncodeToString(raw.NovelID),
+			User:         user,
+			Rating:       raw.Rating,
+			Title:        raw.Title,
+			Content:      raw.Content,
+			Spoiler:      raw.Spoiler,
+			HelpfulCount: raw.HelpfulCount,
+			Reply:        raw.Reply.String,
+			RepliedAt:    raw.RepliedAt.Time,
+			CreateAt:     raw.CreateAt,
+			UpdateAt:     raw.UpdateAt,
+		})
+	}
+	return reviews
+}
+
+// Update the review, the rating in the review is saved as the reviewer's
+// rating of the novel in the same transaction
+func (db *Database) UpdateReview(reviewID []byte, args *model.ReviewMetadata) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var review model.Review
+		err := tx.GetContext(ctx, &review, "SELECT * FROM reviews WHERE id = ? FOR UPDATE", reviewID)
+		if err != nil {
+			return err
+		}
+		if err := setRating(ctx, tx, review.UserID, review.NovelID, args.Rating); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE reviews
+			SET title = ?, content = ?, spoiler = ?, updated_at = ?
+			WHERE id = ?`,
+			args.Title,
+			args.Content,
+			args.Spoiler,
+			time.Now(),
+			reviewID,
+		)
+		return err
+	})
+}
+
+// Delete the review, the reviewer's rating of the novel is kept
+func (db *Database) DeleteReview(reviewID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		return deleteReviews(ctx, tx, [][]byte{reviewID})
+	})
+}
+
+// Mark the review as helpful for the user, voting twice has no effect
+func (db *Database) VoteReviewHelpful(reviewID []byte, userID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			"INSERT IGNORE INTO review_votes (review_id, user_id) VALUES (?,?)",
+			reviewID,
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE reviews SET helpful_count = helpful_count + 1 WHERE id = ?",
+			reviewID,
+		)
+		return err
+	})
+}
+
+func (db *Database) UnvoteReviewHelpful(reviewID []byte, userID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM review_votes WHERE review_id = ? AND user_id = ?",
+			reviewID,
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE reviews SET helpful_count = helpful_count - 1 WHERE id = ?",
+			reviewID,
+		)
+		return err
+	})
+}
+
+// Set the novel author's reply to the review, replying again replaces the old reply
+func (db *Database) ReplyReview(reviewID []byte, reply string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE reviews SET reply = ?, replied_at = ? WHERE id = ?",
+		reply,
+		time.Now(),
+		reviewID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) DeleteReviewReply(reviewID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE reviews SET reply = NULL, replied_at = NULL WHERE id = ?",
+		reviewID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Delete the reviews along with their votes and reports
+func deleteReviews(ctx context.Context, tx *sqlx.Tx, reviewIDs [][]byte) error {
+	deletions := []string{
+		"DELETE FROM review_votes WHERE review_id IN (?)",
+		"DELETE FROM reports WHERE to_id IN (?)",
+		"DELETE FROM reviews WHERE id IN (?)",
+	}
+	for _, query := range deletions {
+		if err := execWithIDs(ctx, tx, query, reviewIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete the reviews written by the user and take the user's votes out of
+// the helpful count of the other reviews
+func deleteUserReviews(ctx context.Context, tx *sqlx.Tx, userID []byte) error {
+	var reviewIDs [][]byte
+	err := tx.SelectContext(ctx, &reviewIDs, "SELECT id FROM reviews WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+	if err := deleteReviews(ctx, tx, reviewIDs); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE reviews
+		INNER JOIN review_votes
+		ON review_votes.review_id = reviews.id AND review_votes.user_id = ?
+		SET reviews.helpful_count = reviews.helpful_count - 1`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM review_votes WHERE user_id = ?", userID)
+	return err
+}
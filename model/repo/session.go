@@ -3,9 +3,11 @@ package repo
 import (
 	"Lightnovel/model"
 	"context"
+	"database/sql"
 	"encoding/hex"
 	"errors"
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
 	"time"
 )
 
@@ -14,24 +16,28 @@ var sessionDuration = time.Hour * 24 * 30
 func (db *Database) CreateSession(
 	userID []byte,
 	deviceName string,
+	ipAddress string,
 ) (model.SessionInfo, bool) {
 	sessionID := GetUUID()
 	expires := time.Now().Add(sessionDuration)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	_, err := db.db.ExecContext(
 		ctx,
-		"INSERT INTO sessions (id, user_id, expires_at, device_name) VALUES (?, ?, ?, ?)",
+		`INSERT INTO sessions (id, user_id, expires_at, device_name, family_id, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?)`,
 		sessionID,
 		userID,
 		expires,
 		deviceName,
+		sessionID,
+		ipAddress,
 	)
 	cancel()
 	if err != nil && !errors.Is(err, context.Canceled) {
 		log.Error(err)
 		return model.SessionInfo{}, false
 	}
-	return model.SessionInfo{hex.EncodeToString(sessionID), expires}, true
+	return model.SessionInfo{Session: hex.EncodeToString(sessionID), ExpiredAt: expires}, true
 }
 
 func (db *Database) GetSession(sessionID []byte) (model.Session, bool) {
@@ -41,7 +47,9 @@ func (db *Database) GetSession(sessionID []byte) (model.Session, bool) {
 	err := db.db.GetContext(
 		ctx,
 		&session,
-		"SELECT id, user_id, expires_at, device_name FROM sessions WHERE id = ?",
+		`SELECT id, user_id, expires_at, device_name, family_id, created_at, last_used_at, ip_address
+		FROM sessions
+		WHERE id = ? AND revoked_at IS NULL`,
 		sessionID,
 	)
 	cancel()
@@ -57,11 +65,167 @@ func (db *Database) GetSession(sessionID []byte) (model.Session, bool) {
 	return session, true
 }
 
+// Return the usable session of the family, the one not replaced yet
+func (db *Database) GetSessionByFamily(familyID []byte) (model.Session, bool) {
+	var session model.Session
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(
+		ctx,
+		&session,
+		`SELECT id, user_id, expires_at, device_name, family_id, created_at, last_used_at, ip_address
+		FROM sessions
+		WHERE family_id = ? AND revoked_at IS NULL AND expires_at > ?`,
+		familyID,
+		time.Now(),
+	)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return session, false
+	}
+	return session, true
+}
+
+// Return the logged in devices of the user, the most recently used first
+func (db *Database) GetUserSessions(userID []byte, currentSessionID []byte) []model.SessionView {
+	var views []model.SessionView
+	var raws []struct {
+		model.Session
+		Current bool `db:"current"`
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&raws,
+		`SELECT id, user_id, expires_at, device_name, family_id, created_at, last_used_at, ip_address,
+			family_id = (SELECT family_id FROM sessions WHERE id = ?) AS current
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_used_at DESC, family_id`,
+		currentSessionID,
+		userID,
+		time.Now(),
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return views
+	}
+
+	for _, raw := range raws {
+		views = append(views, model.SessionView{
+			ID:         hex.EncodeToString(raw.FamilyID),
+			DeviceName: raw.DeviceName,
+			CreatedAt:  raw.CreateAt,
+			LastUsedAt: raw.LastUsedAt,
+			IPAddress:  raw.IPAddress,
+			ExpiredAt:  raw.ExpireAt,
+			Current:    raw.Current,
+		})
+	}
+	return views
+}
+
+// Replace the session with a new session of the same family and return the new
+// session. If the session was already replaced, it is being reused by someone who
+// copied it so the whole family is revoked and false is returned
+func (db *Database) RefreshSession(
+	sessionID []byte,
+	ipAddress string,
+) (model.Session, model.SessionInfo, bool) {
+	var newSession model.Session
+	// The session does not exist or is expired, nothing is changed
+	invalid := false
+	reused := false
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	ok := db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var session struct {
+			model.Session
+			RevokedAt sql.NullTime `db:"revoked_at"`
+		}
+		err := tx.GetContext(
+			ctx,
+			&session,
+			`SELECT id, user_id, expires_at, device_name, family_id, created_at, revoked_at
+			FROM sessions
+			WHERE id = ?
+			FOR UPDATE`,
+			sessionID,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			invalid = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if session.RevokedAt.Valid {
+			reused = true
+			log.Warnf("Session family %x revoked after a replaced session was reused", session.FamilyID)
+			_, err = tx.ExecContext(ctx, "DELETE FROM sessions WHERE family_id = ?", session.FamilyID)
+			return err
+		}
+		if session.ExpireAt.Before(time.Now()) {
+			invalid = true
+			return nil
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = ?",
+			sessionID,
+		)
+		if err != nil {
+			return err
+		}
+
+		newSession = model.Session{
+			ID:         GetUUID(),
+			UserID:     session.UserID,
+			ExpireAt:   time.Now().Add(sessionDuration),
+			DeviceName: session.DeviceName,
+			FamilyID:   session.FamilyID,
+			CreateAt:   session.CreateAt,
+			LastUsedAt: time.Now(),
+			IPAddress:  ipAddress,
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO sessions
+				(id, user_id, expires_at, device_name, family_id, created_at, last_used_at, ip_address)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			newSession.ID,
+			newSession.UserID,
+			newSession.ExpireAt,
+			newSession.DeviceName,
+			newSession.FamilyID,
+			newSession.CreateAt,
+			newSession.LastUsedAt,
+			newSession.IPAddress,
+		)
+		return err
+	})
+	if !ok || invalid || reused {
+		return model.Session{}, model.SessionInfo{}, false
+	}
+
+	return newSession, model.SessionInfo{
+		Session:   hex.EncodeToString(newSession.ID),
+		ExpiredAt: newSession.ExpireAt,
+	}, true
+}
+
+// Delete the session along with every session of its family
 func (db *Database) DeleteSession(sessionID []byte) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	_, err := db.db.ExecContext(
 		ctx,
-		"DELETE FROM sessions WHERE id = ?",
+		`DELETE FROM sessions
+		WHERE family_id = (SELECT family_id FROM (SELECT family_id FROM sessions WHERE id = ?) AS session)`,
 		sessionID,
 	)
 	cancel()
@@ -71,6 +235,25 @@ func (db *Database) DeleteSession(sessionID []byte) bool {
 	return true
 }
 
+// Delete the sessions of the user except the family of the current session
+func (db *Database) DeleteOtherSessions(userID []byte, currentSessionID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		`DELETE FROM sessions
+		WHERE user_id = ?
+			AND family_id <> (SELECT family_id FROM (SELECT family_id FROM sessions WHERE id = ?) AS session)`,
+		userID,
+		currentSessionID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
 func (db *Database) DeleteExpiredSessions() bool {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*10)
 	_, err := db.db.ExecContext(
@@ -0,0 +1,330 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+)
+
+func (db *Database) CreateShelf(userID []byte, args *model.ShelfMetadata) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	uid := GetUUID()
+	_, err := db.db.ExecContext(
+		ctx,
+		`INSERT INTO shelves (id, user_id, name, visibility, position)
+		SELECT ?,?,?,?, COALESCE(MAX(position), 0) + 1
+		FROM shelves
+		WHERE user_id = ?`,
+		uid,
+		userID,
+		args.Name,
+		args.Visibility,
+		userID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return []byte{}, false
+	}
+	return uid, true
+}
+
+func (db *Database) GetShelf(shelfID []byte) (model.Shelf, bool) {
+	var shelf model.Shelf
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &shelf, "SELECT * FROM shelves WHERE id = ?", shelfID)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return shelf, false
+	}
+	return shelf, true
+}
+
+func (db *Database) GetShelfByName(userID []byte, name string) (model.Shelf, bool) {
+	var shelf model.Shelf
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(
+		ctx,
+		&shelf,
+		"SELECT * FROM shelves WHERE user_id = ? AND name = ?",
+		userID,
+		name,
+	)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return shelf, false
+	}
+	return shelf, true
+}
+
+type shelfRaw struct {
+	model.Shelf
+	NovelCount int `db:"novel_count"`
+}
+
+// Return the shelves of the user in the order they set,
+// the private shelves are only included when includePrivate is true
+func (db *Database) GetUserShelves(userID []byte, includePrivate bool) []model.ShelfView {
+	var shelves []model.ShelfView
+	var raws []shelfRaw
+	query := `
+		SELECT shelves.*,
+			(SELECT COUNT(*) FROM shelf_novels
+			WHERE shelf_novels.shelf_id = shelves.id) AS novel_count
+		FROM shelves
+		WHERE user_id = ?`
+	args := []interface{}{userID}
+	if !includePrivate {
+		query += " AND visibility = ?"
+		args = append(args, model.VisibilityPublic)
+	}
+	query += " ORDER BY position"
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(ctx, &raws, query, args...)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return shelves
+	}
+
+	for _, raw := range raws {
+		shelves = append(shelves, model.ShelfView{
+			ID:         hex.EncodeToString(raw.ID),
+			Name:       raw.Name,
+			Visibility: raw.Visibility.String(),
+			NovelCount: raw.NovelCount,
+			CreateAt:   raw.CreateAt,
+			UpdateAt:   raw.UpdateAt,
+		})
+	}
+	return shelves
+}
+
+func (db *Database) UpdateShelf(shelfID []byte, args *model.ShelfMetadata) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE shelves SET name = ?, visibility = ? WHERE id = ?",
+		args.Name,
+		args.Visibility,
+		shelfID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Delete the shelf, the novels on it are left untouched
+func (db *Database) DeleteShelf(shelfID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		deletions := []struct {
+			query string
+			ids   [][]byte
+		}{
+			{"DELETE FROM shelf_novels WHERE shelf_id IN (?)", [][]byte{shelfID}},
+			{"DELETE FROM shelves WHERE id IN (?)", [][]byte{shelfID}},
+		}
+		for _, deletion := range deletions {
+			if err := execWithIDs(ctx, tx, deletion.query, deletion.ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Rewrite the position of the shelves of the user to follow the order of shelfIDs,
+// the transaction is rolled back if shelfIDs is not exactly the shelves of the user
+func (db *Database) ReorderShelves(userID []byte, shelfIDs [][]byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var currentIDs [][]byte
+		err := tx.SelectContext(
+			ctx,
+			&currentIDs,
+			"SELECT id FROM shelves WHERE user_id = ? FOR UPDATE",
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+		if !isSameIDSet(shelfIDs, currentIDs) {
+			return errReorderMismatch
+		}
+
+		for i, shelfID := range shelfIDs {
+			_, err := tx.ExecContext(
+				ctx,
+				"UPDATE shelves SET position = ? WHERE id = ? AND user_id = ?",
+				i+1,
+				shelfID,
+				userID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Put the novel at the end of the shelf, adding it twice has no effect
+func (db *Database) AddNovelToShelf(shelfID []byte, novelID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		`INSERT IGNORE INTO shelf_novels (shelf_id, novel_id, position)
+		SELECT ?,?, COALESCE(MAX(position), 0) + 1
+		FROM shelf_novels
+		WHERE shelf_id = ?`,
+		shelfID,
+		novelID,
+		shelfID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) RemoveNovelFromShelf(shelfID []byte, novelID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"DELETE FROM shelf_novels WHERE shelf_id = ? AND novel_id = ?",
+		shelfID,
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Return the IDs of every novel on the shelf in the order of the shelf
+func (db *Database) GetShelfNovelIDs(shelfID []byte) []string {
+	var novelIDs [][]byte
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&novelIDs,
+		"SELECT novel_id FROM shelf_novels WHERE shelf_id = ? ORDER BY position",
+		shelfID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+	}
+
+	ids := make([]string, len(novelIDs))
+	for i, novelID := range novelIDs {
+		ids[i] = hex.EncodeToString(novelID)
+	}
+	return ids
+}
+
+// Return a page of the public novels on the shelf matching the filters
+func (db *Database) GetShelfNovels(
+	shelfID []byte,
+	filtersAndSort *model.FiltersAndSortNovel,
+) []model.NovelMetadataSmall {
+	var novels []model.NovelMetadataSmall
+	filtersAndSortQuery, filtersAndSortArgs := filtersAndSort.ConstructQuery()
+	query := `
+		SELECT novels.*
+		FROM shelf_novels
+		INNER JOIN novels
+		ON shelf_novels.novel_id = novels.id
+	`
+	if len(filtersAndSort.Tag) != 0 || len(filtersAndSort.TagExclude) != 0 {
+		query += `
+		RIGHT JOIN (
+			SELECT novel_id, GROUP_CONCAT(tag_id) AS tag_groupconcat
+			FROM novel_tags
+			GROUP BY novel_id
+		) AS TABLE1
+		ON TABLE1.novel_id = novels.id`
+	}
+	query += ` WHERE shelf_novels.shelf_id = ? AND novels.visibility = ?
+		AND novels.deleted_at IS NULL` + filtersAndSortQuery
+
+	args := []interface{}{shelfID, model.VisibilityPublic}
+	if filtersAndSortArgs != nil {
+		args = append(args, filtersAndSortArgs...)
+	}
+
+	var raws []model.Novel
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(ctx, &raws, query, args...)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return novels
+	}
+
+	for i := range raws {
+		novel, ok := db.toNovelMetadataSmall(&raws[i])
+		if !ok {
+			return novels
+		}
+		novels = append(novels, novel)
+	}
+	return novels
+}
+
+// Rewrite the position of the novels on the shelf to follow the order of novelIDs,
+// the transaction is rolled back if novelIDs is not exactly the novels on the shelf
+func (db *Database) ReorderShelfNovels(shelfID []byte, novelIDs [][]byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var currentIDs [][]byte
+		err := tx.SelectContext(
+			ctx,
+			&currentIDs,
+			"SELECT novel_id FROM shelf_novels WHERE shelf_id = ? FOR UPDATE",
+			shelfID,
+		)
+		if err != nil {
+			return err
+		}
+		if !isSameIDSet(novelIDs, currentIDs) {
+			return errReorderMismatch
+		}
+
+		for i, novelID := range novelIDs {
+			_, err := tx.ExecContext(
+				ctx,
+				"UPDATE shelf_novels SET position = ? WHERE shelf_id = ? AND novel_id = ?",
+				i+1,
+				shelfID,
+				novelID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
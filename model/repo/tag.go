@@ -0,0 +1,174 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+)
+
+func (db *Database) CreateTag(args *model.TagMetadata) (int, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	res, err := db.db.ExecContext(
+		ctx,
+		"INSERT INTO tags (name, description) VALUES (?,?)",
+		args.Name,
+		args.Description,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return 0, false
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		log.Error(err)
+		return 0, false
+	}
+	return int(id), true
+}
+
+func (db *Database) GetTag(tagID int) (model.Tag, bool) {
+	var tag model.Tag
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &tag, "SELECT * FROM tags WHERE id = ?", tagID)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return tag, false
+	}
+	return tag, true
+}
+
+func (db *Database) GetTagByName(name string) (model.Tag, bool) {
+	var tag model.Tag
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &tag, "SELECT * FROM tags WHERE name = ?", name)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error(err)
+		}
+		return tag, false
+	}
+	return tag, true
+}
+
+// Return a page of the tags whose name starts with the search string in
+// alphabetical order, every tag matches an empty search string
+func (db *Database) FindTags(search string, page uint) []model.Tag {
+	var tags []model.Tag
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.SelectContext(
+		ctx,
+		&tags,
+		`SELECT * FROM tags WHERE name LIKE CONCAT(?, '%') ESCAPE '\\' ORDER BY name LIMIT ? OFFSET ?`,
+		escapeLike(search),
+		model.PageSize,
+		model.PageSize*(page-1),
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+	}
+	return tags
+}
+
+// Check if every tag in the list exists
+func (db *Database) TagsExist(tagIDs []int) bool {
+	if len(tagIDs) == 0 {
+		return true
+	}
+	query, args, err := sqlx.In("SELECT COUNT(DISTINCT id) FROM tags WHERE id IN (?)", tagIDs)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	unique := make(map[int]bool, len(tagIDs))
+	for _, id := range tagIDs {
+		unique[id] = true
+	}
+
+	count := 0
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err = db.db.GetContext(ctx, &count, db.db.Rebind(query), args...)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return count == len(unique)
+}
+
+func (db *Database) UpdateTag(tagID int, args *model.TagMetadata) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE tags SET name = ?, description = ? WHERE id = ?",
+		args.Name,
+		args.Description,
+		tagID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Move every novel of the source tag to the destination tag and delete the source tag
+func (db *Database) MergeTags(fromID int, toID int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT IGNORE INTO novel_tags (novel_id, tag_id)
+			SELECT novel_id, ? FROM novel_tags WHERE tag_id = ?`,
+			toID,
+			fromID,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM novel_tags WHERE tag_id = ?", fromID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM tags WHERE id = ?", fromID)
+		return err
+	})
+}
+
+func (db *Database) SetNovelTags(novelID []byte, tagIDs []int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		return setNovelTags(ctx, tx, novelID, tagIDs)
+	})
+}
+
+// Replace the tags of the novel with the tags in the list
+func setNovelTags(ctx context.Context, tx *sqlx.Tx, novelID []byte, tagIDs []int) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM novel_tags WHERE novel_id = ?", novelID)
+	if err != nil {
+		return err
+	}
+	for _, tagID := range tagIDs {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT IGNORE INTO novel_tags (novel_id, tag_id) VALUES (?,?)",
+			novelID,
+			tagID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const userTokenLength = 32
+
+// Create a token for the user replacing the previous tokens of the same purpose,
+// the returned token is the only copy since the database keeps its hash. False is
+// returned if a token of the purpose was created within its cooldown, the user is
+// locked so concurrent requests cannot all pass the check
+func (db *Database) CreateUserToken(
+	userID []byte,
+	purpose model.TokenPurpose,
+	email string,
+) (string, bool) {
+	raw := make([]byte, userTokenLength)
+	if _, err := rand.Read(raw); err != nil {
+		log.Error(err)
+		return "", false
+	}
+	token := hex.EncodeToString(raw)
+	hash := sha256.Sum256([]byte(token))
+
+	tooSoon := false
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	ok := db.withTx(ctx, func(tx *sqlx.Tx) error {
+		if purpose.Cooldown() > 0 {
+			var id []byte
+			err := tx.GetContext(ctx, &id, "SELECT id FROM users WHERE id = ? FOR UPDATE", userID)
+			if err != nil {
+				return err
+			}
+			count := 0
+			err = tx.GetContext(
+				ctx,
+				&count,
+				"SELECT COUNT(*) FROM user_tokens WHERE user_id = ? AND purpose = ? AND created_at > ?",
+				userID,
+				purpose,
+				time.Now().Add(-purpose.Cooldown()),
+			)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				tooSoon = true
+				return nil
+			}
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM user_tokens WHERE user_id = ? AND purpose = ?",
+			userID,
+			purpose,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO user_tokens (token_hash, user_id, purpose, email, expires_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			hash[:],
+			userID,
+			purpose,
+			email,
+			time.Now().Add(purpose.Duration()),
+		)
+		return err
+	})
+	if !ok || tooSoon {
+		return "", false
+	}
+	return token, true
+}
+
+// Delete the token and return it if it was not expired, a token can only be
+// consumed once
+func (db *Database) ConsumeUserToken(token string, purpose model.TokenPurpose) (model.UserToken, bool) {
+	var userToken model.UserToken
+	valid := false
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	ok := db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		userToken, valid, err = consumeUserToken(ctx, tx, token, purpose)
+		return err
+	})
+	if !ok || !valid {
+		return model.UserToken{}, false
+	}
+	return userToken, true
+}
+
+// Delete the token, valid is false if the token does not exist, is expired or
+// is for another purpose
+func consumeUserToken(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	token string,
+	purpose model.TokenPurpose,
+) (userToken model.UserToken, valid bool, err error) {
+	hash := sha256.Sum256([]byte(token))
+	err = tx.GetContext(
+		ctx,
+		&userToken,
+		"SELECT * FROM user_tokens WHERE token_hash = ? FOR UPDATE",
+		hash[:],
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return userToken, false, nil
+	}
+	if err != nil || userToken.Purpose != purpose {
+		return userToken, false, err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_tokens WHERE token_hash = ?", hash[:])
+	if err != nil {
+		return userToken, false, err
+	}
+	return userToken, !userToken.ExpireAt.Before(time.Now()), nil
+}
+
+// Consume the password reset token, set the new password and end every session
+// of the user. False is returned if the token is not valid or the email it was
+// sent to is no longer the verified email of the user
+func (db *Database) ResetPassword(token string, newPassword []byte) bool {
+	valid := false
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	ok := db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var userToken model.UserToken
+		var err error
+		userToken, valid, err = consumeUserToken(ctx, tx, token, model.TokenResetPassword)
+		if err != nil || !valid {
+			return err
+		}
+
+		res, err := tx.ExecContext(
+			ctx,
+			`UPDATE users SET password = ?
+			WHERE id = ? AND email = ? AND email_verified_at IS NOT NULL`,
+			newPassword,
+			userToken.UserID,
+			userToken.Email,
+		)
+		if err != nil {
+			return err
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count != 1 {
+			valid = false
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userToken.UserID)
+		return err
+	})
+	return ok && valid
+}
+
+func (db *Database) DeleteExpiredUserTokens() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(ctx, "DELETE FROM user_tokens WHERE expires_at < ?", time.Now())
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+var errPendingSecretChanged = errors.New("the pending TOTP secret is not the confirmed one")
+
+// Keep the secret until the user confirms it with a code from the app
+func (db *Database) SetPendingTOTPSecret(userID []byte, secret []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE users SET totp_pending_secret = ? WHERE id = ?",
+		secret,
+		userID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Enable the two-factor authentication with the secret the confirmation code was
+// checked against, the time step of the code is kept so it cannot be used again.
+// False is returned if the pending secret was replaced in the meantime
+func (db *Database) EnableTOTP(
+	userID []byte,
+	secret []byte,
+	counter int64,
+	recoveryCodes []string,
+) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE users
+			SET totp_secret = ?, totp_pending_secret = NULL, totp_last_counter = ?
+			WHERE id = ? AND totp_pending_secret = ? AND totp_secret IS NULL`,
+			secret,
+			counter,
+			userID,
+			secret,
+		)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count != 1 {
+			return errPendingSecretChanged
+		}
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodes)
+	})
+}
+
+func (db *Database) DisableTOTP(userID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`UPDATE users
+			SET totp_secret = NULL, totp_pending_secret = NULL, totp_last_counter = 0
+			WHERE id = ?`,
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID)
+		return err
+	})
+}
+
+// Record the time step of an accepted code, false is returned if a code of this
+// time step or a later one was already accepted
+func (db *Database) UseTOTPCounter(userID []byte, counter int64) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	result, err := db.db.ExecContext(
+		ctx,
+		"UPDATE users SET totp_last_counter = ? WHERE id = ? AND totp_last_counter < ?",
+		counter,
+		userID,
+		counter,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return count == 1
+}
+
+// Count a code about to be checked, false is returned if the user already tried
+// too many codes. The attempt after the last allowed one starts the lock and the
+// count starts again once the lock is over
+func (db *Database) StartTOTPAttempt(userID []byte) bool {
+	now := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	result, err := db.db.ExecContext(
+		ctx,
+		`UPDATE users SET totp_failed_attempts = totp_failed_attempts + 1
+		WHERE id = ? AND totp_failed_attempts < ?
+		AND (totp_locked_until IS NULL OR totp_locked_until <= ?)`,
+		userID,
+		model.TwoFactorMaxAttempts,
+		now,
+	)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	if count == 1 {
+		return true
+	}
+
+	_, err = db.db.ExecContext(
+		ctx,
+		`UPDATE users SET totp_failed_attempts = 0, totp_locked_until = ?
+		WHERE id = ? AND totp_failed_attempts >= ?`,
+		now.Add(model.TwoFactorLockDuration),
+		userID,
+		model.TwoFactorMaxAttempts,
+	)
+	if err != nil {
+		log.Error(err)
+	}
+	return false
+}
+
+// Forget the codes tried before an accepted one
+func (db *Database) ResetTOTPAttempts(userID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		"UPDATE users SET totp_failed_attempts = 0, totp_locked_until = NULL WHERE id = ?",
+		userID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+func (db *Database) ReplaceRecoveryCodes(userID []byte, recoveryCodes []string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodes)
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID []byte, recoveryCodes []string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+	for _, code := range recoveryCodes {
+		hash := sha256.Sum256([]byte(code))
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO recovery_codes (code_hash, user_id) VALUES (?, ?)",
+			hash[:],
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete the recovery code of the user, false is returned if the user has no such code
+func (db *Database) ConsumeRecoveryCode(userID []byte, recoveryCode string) bool {
+	hash := sha256.Sum256([]byte(recoveryCode))
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	result, err := db.db.ExecContext(
+		ctx,
+		"DELETE FROM recovery_codes WHERE code_hash = ? AND user_id = ?",
+		hash[:],
+		userID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return count == 1
+}
+
+func (db *Database) CountRecoveryCodes(userID []byte) int {
+	count := 0
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM recovery_codes WHERE user_id = ?", userID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+	}
+	return count
+}
@@ -1,8 +1,11 @@
 package repo
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"strings"
 )
 
@@ -10,3 +13,66 @@ func GetUUID() []byte {
 	uid, _ := hex.DecodeString(strings.ReplaceAll(uuid.New().String(), "-", ""))
 	return uid
 }
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Escape the LIKE wildcards so the text is matched literally, the query has to
+// use ESCAPE '\\'
+func escapeLike(text string) string {
+	return likeEscaper.Replace(text)
+}
+
+var errReorderMismatch = errors.New("the provided IDs do not match the current ones")
+
+// Check if both slices contain the same IDs, each ID exactly once
+func isSameIDSet(a [][]byte, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, id := range a {
+		seen[string(id)] = true
+	}
+	if len(seen) != len(a) {
+		return false
+	}
+	for _, id := range b {
+		if !seen[string(id)] {
+			return false
+		}
+	}
+	return true
+}
+
+// Execute the query which contains a single "IN (?)" placeholder with the IDs,
+// nothing is executed if there is no ID
+func execWithIDs(ctx context.Context, tx *sqlx.Tx, query string, ids [][]byte) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(query, ids)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), args...)
+	return err
+}
+
+// Return the IDs of the comments on the targets along with all of their replies
+func getCommentTreeIDs(ctx context.Context, tx *sqlx.Tx, targetIDs [][]byte) ([][]byte, error) {
+	var commentIDs [][]byte
+	for len(targetIDs) != 0 {
+		query, args, err := sqlx.In("SELECT id FROM comments WHERE to_id IN (?)", targetIDs)
+		if err != nil {
+			return nil, err
+		}
+		var replyIDs [][]byte
+		err = tx.SelectContext(ctx, &replyIDs, tx.Rebind(query), args...)
+		if err != nil {
+			return nil, err
+		}
+		commentIDs = append(commentIDs, replyIDs...)
+		targetIDs = replyIDs
+	}
+	return commentIDs, nil
+}
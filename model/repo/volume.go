@@ -0,0 +1,212 @@
+package repo
+
+import (
+	"Lightnovel/model"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+)
+
+func (db *Database) CreateVolume(novelID []byte, args *model.VolumeMetadata) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	uid := GetUUID()
+	_, err := db.db.ExecContext(
+		ctx,
+		`INSERT INTO volumes
+		(id, novel_id, title, tagline, description, image, visibility, position)
+		SELECT ?,?,?,?,?,?,?, COALESCE(MAX(position), 0) + 1
+		FROM volumes
+		WHERE novel_id = ?`,
+		uid,
+		novelID,
+		args.Title,
+		args.Tagline,
+		args.Description,
+		args.Image,
+		args.Visibility,
+		novelID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return []byte{}, false
+	}
+	return uid, true
+}
+
+func (db *Database) GetVolume(volumeID []byte) (model.Volume, bool) {
+	var volume model.Volume
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	err := db.db.GetContext(ctx, &volume, "SELECT * FROM volumes WHERE id = ?", volumeID)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return volume, false
+	}
+	return volume, true
+}
+
+func toVolumeView(volume *model.Volume) model.VolumeView {
+	return model.VolumeView{
+		ID:          hex.EncodeToString(volume.ID),
+		NovelID:     hex.EncodeToString(volume.NovelID),
+		Title:       volume.Title,
+		Tagline:     volume.Tagline,
+		Description: volume.Description,
+		Image:       volume.Image,
+		CreateAt:    volume.CreateAt,
+		UpdateAt:    volume.UpdateAt,
+		Views:       volume.Views,
+		Visibility:  volume.Visibility.String(),
+		Position:    volume.Position,
+	}
+}
+
+func (db *Database) GetVolumeView(volumeID []byte) (model.VolumeView, bool) {
+	volume, ok := db.GetVolume(volumeID)
+	if !ok {
+		return model.VolumeView{}, false
+	}
+	return toVolumeView(&volume), true
+}
+
+// Return the volumes of the novel, private volumes are only included
+// when isAuthor is true
+func (db *Database) GetNovelVolumes(novelID []byte, isAuthor bool) []model.VolumeView {
+	var volumes []model.VolumeView
+	query := "SELECT * FROM volumes WHERE novel_id = ?"
+	if isAuthor == false {
+		query += fmt.Sprintf(" AND visibility = %v", int(model.VisibilityPublic))
+	}
+	query += " ORDER BY position, created_at, id"
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	row, err := db.db.QueryxContext(ctx, query, novelID)
+	if err != nil {
+		cancel()
+		log.Error(err)
+		return volumes
+	}
+	defer func() {
+		err := row.Close()
+		if err != nil {
+			log.Error(err)
+		}
+		cancel()
+	}()
+	for row.Next() {
+		var volume model.Volume
+		err := row.StructScan(&volume)
+		if err != nil {
+			log.Error(err)
+			return volumes
+		}
+		volumes = append(volumes, toVolumeView(&volume))
+	}
+	return volumes
+}
+
+func (db *Database) UpdateVolumeMetadata(volumeID []byte, args *model.VolumeMetadata) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	_, err := db.db.ExecContext(
+		ctx,
+		`UPDATE volumes
+		SET title = ?, tagline = ?, description = ?, image = ?, visibility = ?
+		WHERE id = ?`,
+		args.Title,
+		args.Tagline,
+		args.Description,
+		args.Image,
+		args.Visibility,
+		volumeID,
+	)
+	cancel()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return true
+}
+
+// Delete the volume along with its chapters, the comments on them, the reports and
+// the reading progress, the bookmarks and the history entries on them
+func (db *Database) DeleteVolume(volumeID []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var chapterIDs [][]byte
+		err := tx.SelectContext(
+			ctx,
+			&chapterIDs,
+			"SELECT id FROM chapters WHERE volume_id = ?",
+			volumeID,
+		)
+		if err != nil {
+			return err
+		}
+
+		targetIDs := append([][]byte{volumeID}, chapterIDs...)
+		commentIDs, err := getCommentTreeIDs(ctx, tx, targetIDs)
+		if err != nil {
+			return err
+		}
+
+		deletions := []struct {
+			query string
+			ids   [][]byte
+		}{
+			{"DELETE FROM reports WHERE to_id IN (?)", append(targetIDs, commentIDs...)},
+			{"DELETE FROM comments WHERE id IN (?)", commentIDs},
+			{"DELETE FROM reading_progress WHERE chapter_id IN (?)", chapterIDs},
+			{"DELETE FROM chapter_reads WHERE chapter_id IN (?)", chapterIDs},
+			{"DELETE FROM bookmarks WHERE chapter_id IN (?)", chapterIDs},
+			{"DELETE FROM reading_history WHERE chapter_id IN (?)", chapterIDs},
+			{"DELETE FROM chapters WHERE id IN (?)", chapterIDs},
+			{"DELETE FROM volumes WHERE id IN (?)", [][]byte{volumeID}},
+		}
+		for _, deletion := range deletions {
+			if err := execWithIDs(ctx, tx, deletion.query, deletion.ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Rewrite the position of the volumes of the novel to follow the order of volumeIDs,
+// the transaction is rolled back if volumeIDs is not exactly the volumes of the novel
+func (db *Database) ReorderVolumes(novelID []byte, volumeIDs [][]byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), db.timeoutDuration)
+	defer cancel()
+	return db.withTx(ctx, func(tx *sqlx.Tx) error {
+		var currentIDs [][]byte
+		err := tx.SelectContext(
+			ctx,
+			&currentIDs,
+			"SELECT id FROM volumes WHERE novel_id = ? FOR UPDATE",
+			novelID,
+		)
+		if err != nil {
+			return err
+		}
+		if !isSameIDSet(volumeIDs, currentIDs) {
+			return errReorderMismatch
+		}
+
+		for i, volumeID := range volumeIDs {
+			_, err := tx.ExecContext(
+				ctx,
+				"UPDATE volumes SET position = ? WHERE id = ? AND novel_id = ?",
+				i+1,
+				volumeID,
+				novelID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,27 @@
+package model
+
+import "unicode"
+
+// Count the words in the text, each Han, Hiragana or Katakana character is
+// counted as a word since those scripts do not separate words with spaces
+func CountWords(text string) int {
+	count := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			count++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			if !inWord {
+				count++
+				inWord = true
+			}
+		case r == '\'' || r == '’':
+			// Keep contractions like "don't" as a single word
+		default:
+			inWord = false
+		}
+	}
+	return count
+}
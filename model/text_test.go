@@ -0,0 +1,27 @@
+package model
+
+import "testing"
+
+func TestCountWords(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"Empty", "", 0},
+		{"Simple sentence", "The quick brown fox", 4},
+		{"Markdown", "# Content\n ## Content\n **bold** _text_", 4},
+		{"Contraction", "I don't know", 3},
+		{"Punctuation", "Hello,world! 123", 3},
+		{"Japanese", "転生したら", 5},
+		{"Mixed", "Slime 転生", 3},
+		{"Vietnamese", "Thông Nguyễn", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountWords(tt.text); got != tt.want {
+				t.Errorf("CountWords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,56 @@
 package model
 
 import (
+	"strings"
 	"time"
 )
 
 type SessionInfo struct {
+	// The refresh token
 	Session   string    `json:"session"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// The signed token to authenticate the requests with
+	AccessToken     string    `json:"access_token"`
+	AccessExpiredAt time.Time `json:"access_expired_at"`
+}
+
+// A logged in device of the user, the ID is the same across the renewals
+type SessionView struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"deviceName"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	IPAddress  string    `json:"ipAddress"`
+	ExpiredAt  time.Time `json:"expiredAt"`
+	// Whether this is the session making the request
+	Current bool `json:"current"`
+}
+
+// Returned by the login instead of a session when the two-factor authentication
+// is enabled
+type TwoFactorChallenge struct {
+	Challenge string    `json:"challenge"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recoveryCodesLeft"`
+}
+
+type TwoFactorSetup struct {
+	// The secret to type in the authenticator app if the QR code cannot be scanned
+	Secret string `json:"secret"`
+	// The otpauth URI to show as a QR code
+	URI string `json:"uri"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
 }
 
 type IncludeSessionString struct {
-	Session string `json:"session"`
+	Session string `json:"session" form:"session"`
 }
 
 type UserView struct {
@@ -22,6 +62,12 @@ type UserView struct {
 	NovelCount    int       `json:"novelCount"`
 	FollowerCount int       `json:"followCount"`
 	FollowedCount int       `json:"followedCount"`
+	Role          Role      `json:"role"`
+	IsBanned      bool      `json:"isBanned"`
+	// Whether the requesting user follows the user
+	IsFollowing bool `json:"isFollowing"`
+	// Whether the requesting user blocked the user
+	IsBlocking bool `json:"isBlocking"`
 }
 
 type UserMetadata struct {
@@ -32,10 +78,11 @@ type UserMetadata struct {
 }
 
 type UserMetadataSmall struct {
-	ID          string `json:"id"`
-	Username    string `json:"username"`
-	Displayname string `json:"displayName"`
-	Image       string `json:"image"`
+	ID          string        `json:"id"`
+	Username    string        `json:"username"`
+	Displayname string        `json:"displayName"`
+	Image       string        `json:"image"`
+	Images      ImageVariants `json:"images"`
 }
 
 type TagView struct {
@@ -43,6 +90,11 @@ type TagView struct {
 	Name string `json:"name"`
 }
 
+type TagMetadata struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
 type NovelView struct {
 	ID          string            `json:"id"`
 	Title       string            `json:"title"`
@@ -50,6 +102,7 @@ type NovelView struct {
 	Description string            `json:"description"`
 	Author      UserMetadataSmall `json:"author"`
 	Image       string            `json:"image"`
+	Images      ImageVariants     `json:"images"`
 	Language    string            `json:"language"`
 	CreateAt    time.Time         `json:"createAt"    db:"created_at"`
 	UpdateAt    time.Time         `json:"updateAt"    db:"updated_at"`
@@ -63,6 +116,10 @@ type NovelView struct {
 	Tags        []TagView         `json:"tags"`
 	Volumes     int               `json:"volumes"`
 	FollowCount int               `json:"followCount"`
+	// The rating of the requesting user, 0 if the user has not rated the novel
+	MyRating int `json:"myRating"`
+	// Whether the requesting user follows the novel
+	IsFollowing bool `json:"isFollowing"`
 }
 
 type NovelMetadata struct {
@@ -74,6 +131,8 @@ type NovelMetadata struct {
 	Author      []byte        `json:"-"`
 	Visibility  VisibilityID  `json:"visibility"`
 	Status      NovelStatusID `json:"status"`
+	// The IDs of the novel's tags, the tags are left unchanged on update if omitted
+	Tags []int `json:"tags"`
 }
 
 type NovelMetadataSmall struct {
@@ -83,6 +142,7 @@ type NovelMetadataSmall struct {
 	Description string            `json:"description"`
 	Author      UserMetadataSmall `json:"author"`
 	Image       string            `json:"image"`
+	Images      ImageVariants     `json:"images"`
 	Language    string            `json:"language"`
 	TotalRating int               `json:"totalRating" db:"total_rating"`
 	RateCount   int               `json:"rateCount"   db:"rate_count"`
@@ -91,3 +151,257 @@ type NovelMetadataSmall struct {
 	Visibility  string            `json:"visibility"`
 	Views       int               `json:"views"`
 }
+
+type VolumeView struct {
+	ID          string    `json:"id"`
+	NovelID     string    `json:"novelId"`
+	Title       string    `json:"title"`
+	Tagline     string    `json:"tagline"`
+	Description string    `json:"description"`
+	Image       string    `json:"image"`
+	CreateAt    time.Time `json:"createAt"`
+	UpdateAt    time.Time `json:"updateAt"`
+	Views       int       `json:"views"`
+	Visibility  string    `json:"visibility"`
+	Position    int       `json:"position"`
+}
+
+type VolumeMetadata struct {
+	Title       string       `json:"title"`
+	Tagline     string       `json:"tagline"`
+	Description string       `json:"description"`
+	Image       string       `json:"image"`
+	Visibility  VisibilityID `json:"visibility"`
+}
+
+type ChapterView struct {
+	ID            string    `json:"id"`
+	VolumeID      string    `json:"volumeId"`
+	Title         string    `json:"title"`
+	Content       string    `json:"content"`
+	CreateAt      time.Time `json:"createAt"`
+	UpdateAt      time.Time `json:"updateAt"`
+	Views         int       `json:"views"`
+	Visibility    string    `json:"visibility"`
+	Position      int       `json:"position"`
+	WordCount     int       `json:"wordCount"`
+	PrevChapterID string    `json:"prevChapterId"`
+	NextChapterID string    `json:"nextChapterId"`
+}
+
+type ChapterMetadata struct {
+	Title      string       `json:"title"`
+	Content    string       `json:"content"`
+	Visibility VisibilityID `json:"visibility"`
+	WordCount  int          `json:"-"`
+	// The new index of each paragraph of the previous content, used to move the
+	// bookmarks on the chapter when the content is updated
+	ParagraphMap []int `json:"-"`
+}
+
+type ChapterMetadataSmall struct {
+	ID         string    `json:"id"`
+	VolumeID   string    `json:"volumeId"`
+	Title      string    `json:"title"`
+	CreateAt   time.Time `json:"createAt"`
+	UpdateAt   time.Time `json:"updateAt"`
+	Views      int       `json:"views"`
+	Visibility string    `json:"visibility"`
+	Position   int       `json:"position"`
+	WordCount  int       `json:"wordCount"`
+}
+
+type ChapterTOC struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Position   int       `json:"position"`
+	UpdateAt   time.Time `json:"updateAt"`
+	WordCount  int       `json:"wordCount"`
+	Visibility string    `json:"visibility"`
+}
+
+type VolumeTOC struct {
+	ID         string       `json:"id"`
+	Title      string       `json:"title"`
+	Position   int          `json:"position"`
+	UpdateAt   time.Time    `json:"updateAt"`
+	Visibility string       `json:"visibility"`
+	Chapters   []ChapterTOC `json:"chapters"`
+}
+
+type NovelTOC struct {
+	NovelID string      `json:"novelId"`
+	Volumes []VolumeTOC `json:"volumes"`
+}
+
+type ReviewView struct {
+	ID           string            `json:"id"`
+	NovelID      string            `json:"novelId"`
+	User         UserMetadataSmall `json:"user"`
+	Rating       int               `json:"rating"`
+	Title        string            `json:"title"`
+	Content      string            `json:"content"`
+	Spoiler      bool              `json:"spoiler"`
+	HelpfulCount int               `json:"helpfulCount"`
+	// The reply of the novel's author, empty if the author has not replied
+	Reply     string    `json:"reply"`
+	RepliedAt time.Time `json:"repliedAt"`
+	CreateAt  time.Time `json:"createAt"`
+	UpdateAt  time.Time `json:"updateAt"`
+}
+
+type ReviewMetadata struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Spoiler bool   `json:"spoiler"`
+	Rating  int    `json:"rating"`
+}
+
+type CommentView struct {
+	ID         string            `json:"id"`
+	ToID       string            `json:"toId"`
+	User       UserMetadataSmall `json:"user"`
+	Content    string            `json:"content"`
+	ReplyCount int               `json:"replyCount"`
+	CreateAt   time.Time         `json:"createAt"`
+	UpdateAt   time.Time         `json:"updateAt"`
+}
+
+type CommentPage struct {
+	Comments []CommentView `json:"comments"`
+	// Pass as the cursor query to get the next page, empty if there is no more comment
+	NextCursor string `json:"nextCursor"`
+}
+
+type CommentMetadata struct {
+	Content string `json:"content"`
+}
+
+// The URLs of the resized variants of an image, every variant is the image itself
+// if it was not uploaded to the server
+type ImageVariants struct {
+	Small  string `json:"small"`
+	Medium string `json:"medium"`
+	Large  string `json:"large"`
+}
+
+func NewImageVariants(url string) ImageVariants {
+	key, uploaded := strings.CutPrefix(url, ImageURLPath)
+	if !uploaded || key == "" {
+		return ImageVariants{Small: url, Medium: url, Large: url}
+	}
+	return ImageVariants{
+		Small:  ImageURLPath + ImageSmall.Key(key),
+		Medium: ImageURLPath + ImageMedium.Key(key),
+		Large:  ImageURLPath + ImageLarge.Key(key),
+	}
+}
+
+type ReportMetadata struct {
+	ReasonID int    `json:"reasonId"`
+	Details  string `json:"details"`
+}
+
+type ReportReasonCount struct {
+	ReasonID int    `json:"reasonId" db:"reason_id"`
+	Reason   string `json:"reason"`
+	Count    int    `json:"count"`
+}
+
+// The open reports on a single target, as shown in the moderation queue
+type ReportGroup struct {
+	TargetID    string              `json:"targetId"`
+	TargetType  ReportTarget        `json:"targetType"`
+	ReportCount int                 `json:"reportCount"`
+	Reasons     []ReportReasonCount `json:"reasons"`
+	// The details the reporters wrote, empty details are left out
+	Details       []string  `json:"details"`
+	FirstReportAt time.Time `json:"firstReportAt"`
+	LastReportAt  time.Time `json:"lastReportAt"`
+}
+
+type ReportResolutionView struct {
+	ID         int          `json:"id"`
+	TargetID   string       `json:"targetId"`
+	TargetType ReportTarget `json:"targetType"`
+	// Empty if the moderator's account was deleted
+	ModeratorID string       `json:"moderatorId"`
+	Action      ReportAction `json:"action"`
+	Outcome     string       `json:"outcome"`
+	ReportCount int          `json:"reportCount"`
+	CreateAt    time.Time    `json:"createAt"`
+}
+
+type ProgressMetadata struct {
+	// The fraction of the chapter scrolled, from 0 to 1
+	ScrollOffset float64 `json:"scrollOffset"`
+}
+
+// The progress of the user on a single novel
+type NovelProgressView struct {
+	NovelID string `json:"novelId"`
+	// Empty if the user has not started reading the novel
+	ChapterID    string    `json:"chapterId"`
+	ScrollOffset float64   `json:"scrollOffset"`
+	UpdateAt     time.Time `json:"updateAt"`
+	// The IDs of the chapters marked as read
+	ReadChapters []string `json:"readChapters"`
+}
+
+// An entry of the continue reading list
+type ReadingProgressView struct {
+	Novel            NovelMetadataSmall `json:"novel"`
+	ChapterID        string             `json:"chapterId"`
+	ChapterTitle     string             `json:"chapterTitle"`
+	ScrollOffset     float64            `json:"scrollOffset"`
+	ReadChapterCount int                `json:"readChapterCount"`
+	UpdateAt         time.Time          `json:"updateAt"`
+}
+
+type BookmarkMetadata struct {
+	// The index of the paragraph in the chapter content starting from 0
+	Paragraph int    `json:"paragraph"`
+	Note      string `json:"note"`
+}
+
+type BookmarkView struct {
+	ID           string    `json:"id"`
+	ChapterID    string    `json:"chapterId"`
+	ChapterTitle string    `json:"chapterTitle"`
+	Paragraph    int       `json:"paragraph"`
+	Note         string    `json:"note"`
+	CreateAt     time.Time `json:"createAt"`
+}
+
+// The bookmarks of the user on a novel in reading order
+type BookmarkGroup struct {
+	Novel     NovelMetadataSmall `json:"novel"`
+	Bookmarks []BookmarkView     `json:"bookmarks"`
+}
+
+type ShelfMetadata struct {
+	Name       string       `json:"name"`
+	Visibility VisibilityID `json:"visibility"`
+}
+
+type ShelfView struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Visibility string    `json:"visibility"`
+	NovelCount int       `json:"novelCount" db:"novel_count"`
+	CreateAt   time.Time `json:"createAt"`
+	UpdateAt   time.Time `json:"updateAt"`
+}
+
+type HistoryEntryView struct {
+	ID           string    `json:"id"`
+	NovelID      string    `json:"novelId"`
+	NovelTitle   string    `json:"novelTitle"`
+	ChapterID    string    `json:"chapterId"`
+	ChapterTitle string    `json:"chapterTitle"`
+	ReadAt       time.Time `json:"readAt"`
+}
+
+type HistorySettings struct {
+	Enabled bool `json:"enabled"`
+}
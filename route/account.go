@@ -1,50 +1,76 @@
 package route
 
 import (
+	"Lightnovel/mailer"
 	"Lightnovel/middleware"
 	"Lightnovel/model"
+	"Lightnovel/storage"
+	"Lightnovel/token"
+	"bytes"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 	"strings"
+	"time"
 	"unicode"
 	"unicode/utf8"
 )
 
-// TODO: Delete User, Search user by username
-func AddAccountRoutes(router *fiber.Router, db model.DB) {
+func AddAccountRoutes(
+	router *fiber.Router,
+	db model.DB,
+	store storage.Storage,
+	issuer *token.Issuer,
+	mail *mailer.Queue,
+	appURL string,
+) {
 	accountRoute := (*router).Group("/accounts")
 
-	accountRoute.Get("/:username", getUserView(db))
-	accountRoute.Delete("/:username", deleteUser(db))
+	accountRoute.Delete("/:username", deleteUser(db, store))
 
 	accountRoute.Get("/find/:username", searchByUsername(db))
 
-	accountRoute.Post("/register", register(db))
-	accountRoute.Post("/login", login(db))
+	accountRoute.Post("/register", register(db, issuer))
+	accountRoute.Post("/login", login(db, issuer))
+	accountRoute.Post("/login/2fa", loginTwoFactor(db, issuer))
 	accountRoute.Post("/logout", logout(db))
-	accountRoute.Post("/renew", renew(db))
+	accountRoute.Post("/renew", renew(db, issuer))
 	accountRoute.Post("/changepassword", changeUserPassword(db))
-	accountRoute.Post("/self", getUserViewFromSession(db))
-	accountRoute.Post("/followed/users", getFollowedUser(db))
-	accountRoute.Post("/followed/novels", getFollowedNovel(db))
+	addReadRoute(accountRoute, "/self", getUserViewFromSession(db))
+	addReadRoute(accountRoute, "/followed/users", getFollowedUser(db))
+	addReadRoute(accountRoute, "/followed/novels", getFollowedNovel(db))
 
 	accountRoute.Patch("/update", updateUser(db))
 
+	addUserFollowRoutes(accountRoute, db)
+	addUserReportRoutes(accountRoute, db)
+	addUserRoleRoutes(accountRoute, db)
+	addUserBlockRoutes(accountRoute, db)
+	addContinueReadingRoutes(accountRoute, db)
+	addUserBookmarkRoutes(accountRoute, db)
+	addUserShelfRoutes(accountRoute, db)
+	addUserHistoryRoutes(accountRoute, db)
+	addUserSessionRoutes(accountRoute, db)
+	addUserMailRoutes(accountRoute, db, mail, appURL)
+	addUserTwoFactorRoutes(accountRoute, db)
+
+	// Registered last so it does not shadow the other routes of a single segment
+	accountRoute.Get("/:username", getUserView(db))
 }
 
 // Login
 //
 //	@Summary		Log the user in, return a new user session
-//	@Description	The session token should be renewed a week before expires, possible error: WrongPassword, UserNotFound, BadInput, BadPassword, BadUsername, BadDeviceName
+//	@Description	The access token authenticates the requests until it expires, then the session is used as a refresh token to get a new one. Both are also set in HttpOnly cookies. When the two-factor authentication is enabled a challenge is returned with the status 202 instead, the login is finished at /accounts/login/2fa. Possible error: WrongPassword, UserNotFound, UserBanned, BadInput, BadPassword, BadUsername, BadDeviceName
 //	@Tags			accounts
 //	@Accept			json
 //	@Produce		json
 //	@Param			userCredential	body		authCredentials	true	"User credentials"
 //	@Success		200				{object}	model.SessionInfo
+//	@Success		202				{object}	model.TwoFactorChallenge
 //	@Failure		400				{object}	ErrorJSON
 //	@Failure		500
 //	@Router			/accounts/login [POST]
-func login(db model.DB) fiber.Handler {
+func login(db model.DB, issuer *token.Issuer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var authCredentials authCredentials
 		err := c.BodyParser(&authCredentials)
@@ -66,15 +92,35 @@ func login(db model.DB) fiber.Handler {
 		if !passwordGood {
 			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(WrongPassword))
 		}
+		if user.BannedAt.Valid {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(UserBanned))
+		}
+
+		// The session is only created once the code is sent to /login/2fa
+		if user.TOTPSecret != nil {
+			challenge, ok := db.CreateUserToken(user.ID, model.TokenLoginChallenge, "")
+			if !ok {
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}
+			return c.Status(fiber.StatusAccepted).JSON(model.TwoFactorChallenge{
+				Challenge: challenge,
+				ExpiredAt: time.Now().Add(model.TokenLoginChallenge.Duration()),
+			})
+		}
 
 		sessionInfo, ok := db.CreateSession(
 			user.ID,
 			authCredentials.DeviceName,
+			c.IP(),
 		)
 
 		if !ok {
 			return c.SendStatus(fiber.StatusInternalServerError)
 		}
+		if !issueAccessToken(issuer, &sessionInfo, user.ID, user.Role) {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		setSessionCookie(c, sessionInfo)
 		return c.JSON(sessionInfo)
 	}
 }
@@ -91,7 +137,7 @@ func login(db model.DB) fiber.Handler {
 //	@Failure		400				{object}	ErrorJSON
 //	@Failure		500
 //	@Router			/accounts/register [POST]
-func register(db model.DB) fiber.Handler {
+func register(db model.DB, issuer *token.Issuer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var authCredentials authCredentials
 		err := c.BodyParser(&authCredentials)
@@ -120,120 +166,194 @@ func register(db model.DB) fiber.Handler {
 			return c.SendStatus(fiber.StatusInternalServerError)
 		}
 
-		sessionInfo, ok := db.CreateSession(userId, authCredentials.DeviceName)
+		sessionInfo, ok := db.CreateSession(userId, authCredentials.DeviceName, c.IP())
 		if !ok {
 			return c.SendStatus(fiber.StatusInternalServerError)
 		}
+		if !issueAccessToken(issuer, &sessionInfo, userId, model.RoleReader) {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		setSessionCookie(c, sessionInfo)
 		return c.Status(fiber.StatusCreated).JSON(sessionInfo)
 	}
 }
 
 // Logout
 //
-//	@Summary	Log the user out
-//	@Tags		accounts
-//	@Accept		json
-//	@Param		sessionString	body	model.IncludeSessionString	true	"User credentials"
-//	@Success	200
-//	@Failure	400
-//	@Router		/accounts/logout [POST]
+//	@Summary		Log the user out
+//	@Description	The session of the access token is ended, or the session sent as the refresh token if the access token is missing. The session cookies are cleared
+//	@Tags			accounts
+//	@Accept			json
+//	@Param			sessionString	body	model.IncludeSessionString	false	"Refresh token"
+//	@Success		200
+//	@Failure		400
+//	@Router			/accounts/logout [POST]
 func logout(db model.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var sessionStr model.IncludeSessionString
-		err := c.BodyParser(&sessionStr)
-		if err != nil {
-			return c.SendStatus(fiber.StatusBadRequest)
-		}
-		session, err := Unhex(sessionStr.Session)
-		if err != nil {
-			return c.SendStatus(fiber.StatusBadRequest)
-		}
-		_ = db.DeleteSession(session)
+		var sessionID []byte
+		if session, ok := c.Locals(middleware.KeyUserSession).(model.Session); ok {
+			sessionID = session.ID
+		} else {
+			var ok bool
+			sessionID, ok = getRefreshToken(c)
+			if !ok {
+				return c.SendStatus(fiber.StatusBadRequest)
+			}
+		}
+
+		_ = db.DeleteSession(sessionID)
+		clearSessionCookies(c)
 		return c.SendStatus(fiber.StatusOK)
 	}
 }
 
 // Renew
 //
-//	@Summary	Renew the session token, the token should be renewed a week before expires
-//	@Tags		accounts
-//	@Accept		json
-//	@Produce	json
-//	@Param		sessionString	body		model.IncludeSessionString	true	"User credentials"
-//	@Success	200				{object}	model.SessionInfo
-//	@Failure	400
-//	@Failure	401
-//	@Failure	500
-//	@Router		/accounts/renew [POST]
-func renew(db model.DB) fiber.Handler {
+//	@Summary		Exchange the session for a new session and a new access token
+//	@Description	The session is read from the refresh cookie or from the body and can only be used once. Using a session that was already exchanged ends every session descending from the same login
+//	@Tags			accounts
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionString	body		model.IncludeSessionString	false	"Refresh token"
+//	@Success		200				{object}	model.SessionInfo
+//	@Failure		400
+//	@Failure		401
+//	@Failure		500
+//	@Router			/accounts/renew [POST]
+func renew(db model.DB, issuer *token.Issuer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var oldSessionStr model.IncludeSessionString
-		err := c.BodyParser(&oldSessionStr)
-		if err != nil {
-			return c.SendStatus(fiber.StatusBadRequest)
-		}
-
-		oldSession, err := Unhex(oldSessionStr.Session)
-		if err != nil {
+		oldSession, ok := getRefreshToken(c)
+		if !ok {
 			return c.SendStatus(fiber.StatusBadRequest)
 		}
 
-		session, ok := db.GetSession(oldSession)
+		session, newSessionInfo, ok := db.RefreshSession(oldSession, c.IP())
 		if !ok {
+			clearSessionCookies(c)
 			return c.SendStatus(fiber.StatusUnauthorized)
 		}
 
-		newSessionInfo, ok := db.CreateSession(session.UserID, session.DeviceName)
+		// The role is read again so role changes apply from the next access token
+		user, ok := db.GetUserByID(session.UserID)
 		if !ok {
 			return c.SendStatus(fiber.StatusInternalServerError)
 		}
+		if user.BannedAt.Valid {
+			_ = db.DeleteSession(session.ID)
+			clearSessionCookies(c)
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
 
-		_ = db.DeleteSession(oldSession)
+		if !issueAccessToken(issuer, &newSessionInfo, user.ID, user.Role) {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		setSessionCookie(c, newSessionInfo)
 		return c.JSON(newSessionInfo)
 	}
 }
 
+const (
+	NovelActionDelete   = "delete"
+	NovelActionTransfer = "transfer"
+)
+
+type deleteUserInput struct {
+	Password string `json:"password"`
+	// What to do with the user's novels, either "delete" or "transfer"
+	NovelAction string `json:"novelAction"`
+	// Username of the user who receives the novels when NovelAction is "transfer"
+	TransferTo string `json:"transferTo"`
+	// Required when the two-factor authentication is enabled
+	Code string `json:"code"`
+}
+
+func (input *deleteUserInput) Validate() (bool, ErrorCode) {
+	if IsPasswordValid(input.Password) == false {
+		return false, BadPassword
+	}
+
+	switch input.NovelAction {
+	case NovelActionDelete:
+	case NovelActionTransfer:
+		if IsUsernameValid(input.TransferTo) == false {
+			return false, BadUsername
+		}
+	default:
+		return false, BadNovelAction
+	}
+
+	return true, BadInput
+}
+
 // Delete User
 //
-//	@Deprecated
 //	@Summary		Delete user's account and all other data
-//	@Description	Possible error: BadInput, BadPassword, BadUsername, UserNotFound
+//	@Description	The user's novels are either deleted or transferred to another user, all the sessions of the user are revoked. Possible error: BadInput, BadPassword, BadUsername, WrongPassword, TwoFactorRequired, WrongTwoFactorCode, TwoFactorLocked, BadNovelAction, UserNotFound
 //	@Tags			accounts
 //	@Accept			json
-//	@Param			userCredential	body	requiredCredential	true	"User credentials"
+//	@Param			username		path	string						true	"Username"
+//	@Param			input			body	deleteUserInput				true	"Password and what to do with the novels"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's session"
 //	@Success		200
 //	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
 //	@Failure		500
-//	@Router			/accounts/delete [DELETE]
-func deleteUser(db model.DB) fiber.Handler {
+//	@Router			/accounts/:username [DELETE]
+func deleteUser(db model.DB, store storage.Storage) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		return c.SendStatus(fiber.StatusNotImplemented)
-		//var credential requiredCredential
-		//err := c.BodyParser(&credential)
-		//if err != nil {
-		//	return c.SendStatus(fiber.StatusBadRequest)
-		//}
-		//
-		//if ok, code := credential.Validate(); !ok {
-		//	return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
-		//}
-		//
-		//user, ok := db.GetUser(credential.Username)
-		//if !ok {
-		//	return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(UserNotFound))
-		//}
-		//
-		//passwordGood := PasswordVerify(credential.Password, user.Password)
-		//if !passwordGood {
-		//	return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(WrongPassword))
-		//}
-		//
-		//db.DeleteAllSessions(user.ID)
-		//ok = db.DeleteUser(user.ID)
-		//if !ok {
-		//	return c.SendStatus(fiber.StatusInternalServerError)
-		//}
-		//return c.SendStatus(fiber.StatusOK)
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input deleteUserInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		if ok, code := input.Validate(); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUserByID(session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if user.Username != c.Params("username") {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		if PasswordVerify(input.Password, user.Password) == false {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(WrongPassword))
+		}
+		if ok, code := checkTwoFactorCode(db, &user, input.Code); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		var heirID []byte
+		if input.NovelAction == NovelActionTransfer {
+			heir, ok := db.GetUser(input.TransferTo)
+			if !ok || bytes.Equal(heir.ID, user.ID) {
+				return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(UserNotFound))
+			}
+			heirID = heir.ID
+		}
+
+		// The sessions are deleted with the user
+		imageKeys := db.GetUserImageKeys(user.ID)
+		ok = db.DeleteUser(user.ID, heirID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		// The images of the transferred novels are kept by the heir
+		deleteImageFiles(db, store, imageKeys)
+		return c.SendStatus(fiber.StatusOK)
 	}
 }
 
@@ -289,8 +409,9 @@ func updateUser(db model.DB) fiber.Handler {
 //
 //	@Summary	Get user's metadata
 //	@Tags		accounts
-//	@Param		userID	path		string	true	"UserId"
-//	@Success	200		{object}	model.UserView
+//	@Param		userID			path		string						true	"UserId"
+//	@Param		sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success	200				{object}	model.UserView
 //	@Failure	404
 //	@Failure	500
 //	@Router		/accounts/:username [GET]
@@ -303,6 +424,13 @@ func getUserView(db model.DB) fiber.Handler {
 			return c.SendStatus(fiber.StatusNotFound)
 		}
 
+		if c.Locals(middleware.KeyIsUserAuth) == true {
+			session, _ := c.Locals(middleware.KeyUserSession).(model.Session)
+			userID, _ := Unhex(userView.ID)
+			userView.IsFollowing = db.IsFollowingUser(session.UserID, userID)
+			userView.IsBlocking = db.IsBlocking(session.UserID, userID)
+		}
+
 		return c.JSON(userView)
 	}
 }
@@ -310,6 +438,8 @@ func getUserView(db model.DB) fiber.Handler {
 type changePasswordCredential struct {
 	OldPassword string `json:"oldPassword"`
 	NewPassword string `json:"newPassword"`
+	// Required when the two-factor authentication is enabled
+	Code string `json:"code"`
 }
 
 func (cr *changePasswordCredential) Validate() (bool, ErrorCode) {
@@ -323,7 +453,7 @@ func (cr *changePasswordCredential) Validate() (bool, ErrorCode) {
 // Change Password
 //
 //	@Summary		Change user's password
-//	@Description	Possible error: BadInput, BadPassword, WrongPassword
+//	@Description	The other sessions of the user are ended. Possible error: BadInput, BadPassword, WrongPassword, TwoFactorRequired, WrongTwoFactorCode, TwoFactorLocked
 //	@Tags			accounts
 //	@Accept			json
 //	@Param			credential		body	changePasswordCredential	true	"Old and new password"
@@ -367,7 +497,16 @@ func changeUserPassword(db model.DB) fiber.Handler {
 		if PasswordVerify(input.OldPassword, user.Password) == false {
 			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(WrongPassword))
 		}
-		db.UpdateUserPassword(user.ID, newHashed)
+		if ok, code := checkTwoFactorCode(db, &user, input.Code); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+		if !db.UpdateUserPassword(user.ID, newHashed) {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		// The other devices have to log in with the new password
+		if !db.DeleteOtherSessions(user.ID, session.ID) {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
 		return c.SendStatus(fiber.StatusOK)
 	}
 }
@@ -382,7 +521,7 @@ func changeUserPassword(db model.DB) fiber.Handler {
 //	@Success	200				{object}	model.UserView
 //	@Failure	401
 //	@Failure	500
-//	@Router		/accounts/self [POST]
+//	@Router		/accounts/self [GET]
 func getUserViewFromSession(db model.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		if c.Locals(middleware.KeyIsUserAuth) == false {
@@ -412,7 +551,7 @@ func getUserViewFromSession(db model.DB) fiber.Handler {
 //	@Success	200				{object}	[]model.UserMetadataSmall
 //	@Failure	401
 //	@Failure	500
-//	@Router		/accounts/followed/users [POST]
+//	@Router		/accounts/followed/users [GET]
 func getFollowedUser(db model.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		if c.Locals(middleware.KeyIsUserAuth) == false {
@@ -439,7 +578,7 @@ func getFollowedUser(db model.DB) fiber.Handler {
 //	@Success	200				{object}	[]model.NovelMetadataSmall
 //	@Failure	401
 //	@Failure	500
-//	@Router		/accounts/followed/novels [POST]
+//	@Router		/accounts/followed/novels [GET]
 func getFollowedNovel(db model.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		if c.Locals(middleware.KeyIsUserAuth) == false {
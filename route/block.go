@@ -0,0 +1,131 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+func addUserBlockRoutes(accountRoute fiber.Router, db model.DB) {
+	addReadRoute(accountRoute, "/blocked", getBlockedUsers(db))
+	accountRoute.Post("/:username/block", blockUser(db))
+
+	accountRoute.Delete("/:username/block", unblockUser(db))
+}
+
+// Block User
+//
+//	@Summary		Block the user with provided username
+//	@Description	The blocked user cannot follow, comment on the novels of or reply to the blocker, the follows between them are removed. The blocker no longer sees the blocked user's novels in the search or their comments. Blocking twice has no effect, possible error code: SelfBlock
+//	@Tags			block
+//	@Accept			json
+//	@Param			username		path	string						true	"Username"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/accounts/:username/block [POST]
+func blockUser(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if bytes.Equal(session.UserID, user.ID) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(SelfBlock))
+		}
+
+		ok = db.BlockUser(session.UserID, user.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Unblock User
+//
+//	@Summary	Unblock the user with provided username
+//	@Tags		block
+//	@Accept		json
+//	@Param		username		path	string						true	"Username"
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/accounts/:username/block [DELETE]
+func unblockUser(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		ok = db.UnblockUser(session.UserID, user.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Get Blocked Users
+//
+//	@Summary	Get a page of the users the logged in user blocked in alphabetical order
+//	@Tags		block
+//	@Accept		json
+//	@Produce	json
+//	@Param		page			query		uint						false	"Page"
+//	@Param		sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success	200				{object}	[]model.UserMetadataSmall
+//	@Failure	401
+//	@Failure	500
+//	@Router		/accounts/blocked [GET]
+func getBlockedUsers(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		page := c.QueryInt(QueryPage, 1)
+		pageUint := uint(page)
+		if page < 1 {
+			pageUint = 1
+		}
+
+		return c.JSON(db.GetBlockedUsers(session.UserID, pageUint))
+	}
+}
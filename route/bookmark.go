@@ -0,0 +1,169 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"strings"
+	"unicode/utf8"
+)
+
+func addBookmarkRoutes(novelRoute fiber.Router, db model.DB) {
+	novelRoute.Post("/:novelID/chapter/:chapterID/bookmark", createBookmark(db))
+}
+
+func addUserBookmarkRoutes(accountRoute fiber.Router, db model.DB) {
+	addReadRoute(accountRoute, "/bookmarks", getBookmarks(db))
+
+	accountRoute.Delete("/bookmarks/:bookmarkID", deleteBookmark(db))
+}
+
+type createBookmarkResult struct {
+	BookmarkID string `json:"bookmarkId"`
+}
+
+// Create Bookmark
+//
+//	@Summary		Bookmark a paragraph of the chapter with an optional private note, return the created bookmark id
+//	@Description	The paragraph is the index of the paragraph in the chapter content starting from 0, paragraphs are separated by blank lines. Possible error code: BadInput, BadParagraph, BookmarkNoteTooLong
+//	@Tags			bookmark
+//	@Accept			json
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			ChapterID		path		string						true	"Chapter ID"
+//	@Param			bookmark		body		model.BookmarkMetadata		true	"Bookmark"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		201				{object}	createBookmarkResult
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/chapter/:chapterID/bookmark [POST]
+func createBookmark(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.BookmarkMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, chapter, status := getReadableChapter(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if ok, code := checkBookmarkMetadata(&input, &chapter); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		uid, ok := db.CreateBookmark(session.UserID, novel.ID, chapter.ID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(
+			createBookmarkResult{
+				BookmarkID: hex.EncodeToString(uid),
+			})
+	}
+}
+
+// Get Bookmarks
+//
+//	@Summary	Get the bookmarks of the user grouped by novel
+//	@Tags		bookmark
+//	@Accept		json
+//	@Produce	json
+//	@Param		sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success	200				{object}	[]model.BookmarkGroup
+//	@Failure	401
+//	@Failure	500
+//	@Router		/accounts/bookmarks [GET]
+func getBookmarks(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.JSON(db.GetBookmarks(session.UserID))
+	}
+}
+
+// Delete Bookmark
+//
+//	@Summary	Delete the bookmark with provided bookmark id
+//	@Tags		bookmark
+//	@Accept		json
+//	@Param		BookmarkID		path	string						true	"Bookmark ID"
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/accounts/bookmarks/:bookmarkID [DELETE]
+func deleteBookmark(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		bookmarkID, ok := getIDParam(c, "bookmarkID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		bookmark, ok := db.GetBookmark(bookmarkID)
+		if !ok || !bytes.Equal(bookmark.UserID, session.UserID) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		ok = db.DeleteBookmark(bookmarkID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Check the bookmark against the paragraphs of the chapter it is placed in
+func checkBookmarkMetadata(input *model.BookmarkMetadata, chapter *model.Chapter) (bool, ErrorCode) {
+	paragraphCount := len(splitParagraphs(chapter.Content))
+	if paragraphCount == 0 {
+		// An empty chapter can still be bookmarked at its start
+		paragraphCount = 1
+	}
+	if input.Paragraph < 0 || input.Paragraph >= paragraphCount {
+		return false, BadParagraph
+	}
+
+	input.Note = strings.TrimSpace(input.Note)
+	if utf8.RuneCountInString(input.Note) > model.BookmarkNoteMaxLength {
+		return false, BookmarkNoteTooLong
+	}
+
+	return true, BadInput
+}
@@ -0,0 +1,373 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"unicode/utf8"
+)
+
+func addChapterRoutes(novelRoute fiber.Router, db model.DB) {
+	addReadRoute(novelRoute, "/:novelID/volume/:volumeID/chapter", getVolumeChapters(db))
+	novelRoute.Post("/:novelID/volume/:volumeID/chapter/create", createChapter(db))
+	addReadRoute(novelRoute, "/:novelID/chapter/:chapterID", getChapter(db))
+
+	novelRoute.Patch("/:novelID/volume/:volumeID/chapter/order", reorderChapters(db))
+	novelRoute.Patch("/:novelID/chapter/:chapterID", updateChapter(db))
+
+	novelRoute.Delete("/:novelID/chapter/:chapterID", deleteChapter(db))
+}
+
+// Get Volume's Chapters
+//
+//	@Summary		Get all the chapters of the volume without their content
+//	@Description	Private chapters are only returned to the author, if the volume or the novel is private, the user need to be logged in with the author account
+//	@Tags			chapter
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			VolumeID		path		string						true	"Volume ID"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	[]model.ChapterMetadataSmall
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/volume/:volumeID/chapter [GET]
+func getVolumeChapters(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		novel, volume, status := getNovelAndVolume(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		isAuthor := isSessionUser(c, novel.Author)
+		if novel.Visibility == model.VisibilityPrivate ||
+			volume.Visibility == model.VisibilityPrivate {
+			if !isAuthor {
+				return c.SendStatus(fiber.StatusUnauthorized)
+			}
+		}
+
+		return c.JSON(db.GetVolumeChapters(volume.ID, isAuthor))
+	}
+}
+
+// Get Chapter
+//
+//	@Summary		Get the chapter with provided chapter id along with the previous and next chapter id
+//	@Description	The previous and next chapter id can be from other volumes, they are empty if there is no such chapter. If the chapter, its volume or its novel is private, the user need to be logged in with the author account. The chapter is added to the reading history of the logged in user unless they disabled it
+//	@Tags			chapter
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			ChapterID		path		string						true	"Chapter ID"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	model.ChapterView
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/chapter/:chapterID [GET]
+func getChapter(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		novel, volume, chapter, status := getNovelVolumeAndChapter(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		isAuthor := isSessionUser(c, novel.Author)
+		if novel.Visibility == model.VisibilityPrivate ||
+			volume.Visibility == model.VisibilityPrivate ||
+			chapter.Visibility == model.VisibilityPrivate {
+			if !isAuthor {
+				return c.SendStatus(fiber.StatusUnauthorized)
+			}
+		}
+
+		chapterView, ok := db.GetChapterView(novel.ID, chapter.ID, isAuthor)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		if viewerID := getViewerID(c); viewerID != nil {
+			// Failing to record the history should not prevent reading
+			db.RecordHistory(viewerID, novel.ID, chapter.ID)
+		}
+		return c.JSON(chapterView)
+	}
+}
+
+type createChapterResult struct {
+	ChapterID string `json:"chapter_id"`
+}
+
+// Create Chapter
+//
+//	@Summary		Create a new chapter in the volume with the provided metadata, return the created chapter id
+//	@Description	Only the author of the novel can create chapters, possible error code: BadInput, TitleTooLong, ContentTooLong
+//	@Tags			chapter
+//	@Accept			json
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			VolumeID		path		string						true	"Volume ID"
+//	@Param			ChapterDetails	body		model.ChapterMetadata		true	"Chapter details"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		201				{object}	createChapterResult
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/volume/:volumeID/chapter/create [POST]
+func createChapter(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.ChapterMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		if ok, code := checkChapterMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+		input.WordCount = model.CountWords(input.Content)
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		volumeID, status := getAuthorsVolume(c, db, session)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		uid, ok := db.CreateChapter(volumeID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(
+			createChapterResult{
+				ChapterID: hex.EncodeToString(uid),
+			})
+	}
+}
+
+// Update Chapter
+//
+//	@Summary		Update the chapter with the provided metadata and content
+//	@Description	Only the author of the novel can update chapters. The bookmarks on the chapter follow their paragraph, or move to the nearest paragraph if it was removed. Possible error code: BadInput, TitleTooLong, ContentTooLong
+//	@Tags			chapter
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			ChapterID		path	string						true	"Chapter ID"
+//	@Param			ChapterDetails	body	model.ChapterMetadata		true	"Chapter details"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/chapter/:chapterID [PATCH]
+func updateChapter(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.ChapterMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		if ok, code := checkChapterMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+		input.WordCount = model.CountWords(input.Content)
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, _, chapter, status := getNovelVolumeAndChapter(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		if input.Content != chapter.Content {
+			input.ParagraphMap = mapParagraphs(
+				splitParagraphs(chapter.Content),
+				splitParagraphs(input.Content),
+			)
+		}
+
+		ok = db.UpdateChapter(chapter.ID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Delete Chapter
+//
+//	@Summary		Delete the chapter with the provided chapter id
+//	@Description	Only the author of the novel can delete chapters
+//	@Tags			chapter
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			ChapterID		path	string						true	"Chapter ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/chapter/:chapterID [DELETE]
+func deleteChapter(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, _, chapter, status := getNovelVolumeAndChapter(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.DeleteChapter(chapter.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Reorder Chapters
+//
+//	@Summary		Rewrite the reading order of the chapters in the volume
+//	@Description	The list must contain the id of every chapter in the volume exactly once, only the author of the novel can reorder chapters, possible error code: BadInput, BadOrder
+//	@Tags			chapter
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			VolumeID		path	string						true	"Volume ID"
+//	@Param			order			body	reorderInput				true	"Chapter ids in the new order"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/volume/:volumeID/chapter/order [PATCH]
+func reorderChapters(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input reorderInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		chapterIDs, ok := decodeIDs(input.IDs)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadOrder))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		volumeID, status := getAuthorsVolume(c, db, session)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		chapters := db.GetVolumeChapters(volumeID, true)
+		existing := make([]string, 0, len(chapters))
+		for _, chapter := range chapters {
+			existing = append(existing, chapter.ID)
+		}
+		if !isSameIDList(input.IDs, existing) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadOrder))
+		}
+
+		ok = db.ReorderChapters(volumeID, chapterIDs)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Return the novel from the path, the chapter from the path and its volume if
+// the chapter belongs to the novel, otherwise return the status code to response with
+func getNovelVolumeAndChapter(
+	c *fiber.Ctx,
+	db model.DB,
+) (model.Novel, model.Volume, model.Chapter, int) {
+	novelID, ok := getIDParam(c, "novelID")
+	if !ok {
+		return model.Novel{}, model.Volume{}, model.Chapter{}, fiber.StatusNotFound
+	}
+	chapterID, ok := getIDParam(c, "chapterID")
+	if !ok {
+		return model.Novel{}, model.Volume{}, model.Chapter{}, fiber.StatusNotFound
+	}
+
+	novel, ok := db.GetNovel(novelID)
+	if !ok {
+		return model.Novel{}, model.Volume{}, model.Chapter{}, fiber.StatusNotFound
+	}
+	chapter, ok := db.GetChapter(chapterID)
+	if !ok {
+		return model.Novel{}, model.Volume{}, model.Chapter{}, fiber.StatusNotFound
+	}
+	volume, ok := db.GetVolume(chapter.VolumeID)
+	if !ok || bytes.Compare(volume.NovelID, novelID) != 0 {
+		return model.Novel{}, model.Volume{}, model.Chapter{}, fiber.StatusNotFound
+	}
+
+	return novel, volume, chapter, fiber.StatusOK
+}
+
+func checkChapterMetadata(input *model.ChapterMetadata) (bool, ErrorCode) {
+	if utf8.RuneCountInString(input.Title) > model.TitleMaxLength {
+		return false, TitleTooLong
+	}
+
+	if len(input.Content) > model.ContentMaxLength {
+		return false, ContentTooLong
+	}
+
+	if input.Visibility.String() == model.Unknown {
+		return false, BadInput
+	}
+
+	return true, BadInput
+}
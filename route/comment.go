@@ -0,0 +1,292 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"unicode/utf8"
+)
+
+func addCommentRoutes(novelRoute fiber.Router, db model.DB) {
+	addReadRoute(novelRoute, "/:novelID/comment/:targetID", getComments(db))
+	novelRoute.Post("/:novelID/comment/:targetID/create", createComment(db))
+
+	novelRoute.Patch("/:novelID/comment/:commentID", updateComment(db))
+
+	novelRoute.Delete("/:novelID/comment/:commentID", deleteComment(db))
+}
+
+// Get Comments
+//
+//	@Summary		Get a page of the comments on the target from the newest to the oldest
+//	@Description	The target can be the novel, one of its volumes or chapters, or a comment to get its replies. If the novel, volume or chapter is private, the user need to be logged in with the author account. The comments of the users the logged in user blocked are left out
+//	@Tags			comment
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			TargetID		path		string						true	"Novel, Volume, Chapter or Comment ID"
+//	@Param			cursor			query		string						false	"The next cursor of the previous page"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	model.CommentPage
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/comment/:targetID [GET]
+func getComments(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		_, targetID, status := getCommentTarget(c, db, "targetID")
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		comments, next := db.GetComments(
+			targetID,
+			getViewerID(c),
+			decodeCursor(c.Query(QueryCursor, "")),
+		)
+		return c.JSON(model.CommentPage{
+			Comments:   comments,
+			NextCursor: encodeCursor(next),
+		})
+	}
+}
+
+type createCommentResult struct {
+	CommentID string `json:"comment_id"`
+}
+
+// Create Comment
+//
+//	@Summary		Comment on the target, return the created comment id
+//	@Description	The target can be the novel, one of its volumes or chapters, or a comment to reply to it. The user cannot comment on the novels of a user who blocked them, nor reply to their comments. Possible error code: BadInput, CommentTooLong, BlockedByUser
+//	@Tags			comment
+//	@Accept			json
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			TargetID		path		string						true	"Novel, Volume, Chapter or Comment ID"
+//	@Param			comment			body		model.CommentMetadata		true	"Comment"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		201				{object}	createCommentResult
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/comment/:targetID/create [POST]
+func createComment(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.CommentMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkCommentMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, targetID, status := getCommentTarget(c, db, "targetID")
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if db.IsBlocking(novel.Author, session.UserID) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BlockedByUser))
+		}
+		if comment, ok := db.GetComment(targetID); ok && db.IsBlocking(comment.UserID, session.UserID) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BlockedByUser))
+		}
+
+		uid, ok := db.CreateComment(targetID, session.UserID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(
+			createCommentResult{
+				CommentID: hex.EncodeToString(uid),
+			})
+	}
+}
+
+// Update Comment
+//
+//	@Summary		Edit the content of the comment
+//	@Description	Only the commenter can edit the comment. Possible error code: BadInput, CommentTooLong
+//	@Tags			comment
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			CommentID		path	string						true	"Comment ID"
+//	@Param			comment			body	model.CommentMetadata		true	"Comment"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/comment/:commentID [PATCH]
+func updateComment(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.CommentMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkCommentMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		_, comment, status := getNovelAndComment(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if bytes.Compare(session.UserID, comment.UserID) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.UpdateComment(comment.ID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Delete Comment
+//
+//	@Summary		Delete the comment along with all of its replies
+//	@Description	Only the commenter or the author of the novel can delete the comment
+//	@Tags			comment
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			CommentID		path	string						true	"Comment ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/comment/:commentID [DELETE]
+func deleteComment(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, comment, status := getNovelAndComment(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if bytes.Compare(session.UserID, comment.UserID) != 0 &&
+			bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.DeleteComment(comment.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Return the novel and the ID of the comment target from the path if the target is
+// the novel, one of its volumes or chapters, or a comment in their threads, and the
+// user can view it, otherwise return the status code to response with
+func getCommentTarget(c *fiber.Ctx, db model.DB, key string) (model.Novel, []byte, int) {
+	novelID, ok := getIDParam(c, "novelID")
+	if !ok {
+		return model.Novel{}, nil, fiber.StatusNotFound
+	}
+	targetID, ok := getIDParam(c, key)
+	if !ok {
+		return model.Novel{}, nil, fiber.StatusNotFound
+	}
+
+	novel, ok := db.GetNovel(novelID)
+	if !ok {
+		return model.Novel{}, nil, fiber.StatusNotFound
+	}
+	isAuthor := isSessionUser(c, novel.Author)
+	if novel.Visibility == model.VisibilityPrivate && !isAuthor {
+		return model.Novel{}, nil, fiber.StatusUnauthorized
+	}
+
+	rootID, ok := db.GetCommentRootTarget(targetID)
+	if !ok {
+		return model.Novel{}, nil, fiber.StatusInternalServerError
+	}
+	if bytes.Equal(rootID, novelID) {
+		return novel, targetID, fiber.StatusOK
+	}
+
+	volumeID := rootID
+	if chapter, ok := db.GetChapter(rootID); ok {
+		if chapter.Visibility == model.VisibilityPrivate && !isAuthor {
+			return model.Novel{}, nil, fiber.StatusUnauthorized
+		}
+		volumeID = chapter.VolumeID
+	}
+	volume, ok := db.GetVolume(volumeID)
+	if !ok || bytes.Compare(volume.NovelID, novelID) != 0 {
+		return model.Novel{}, nil, fiber.StatusNotFound
+	}
+	if volume.Visibility == model.VisibilityPrivate && !isAuthor {
+		return model.Novel{}, nil, fiber.StatusUnauthorized
+	}
+
+	return novel, targetID, fiber.StatusOK
+}
+
+// Return the novel and the comment from the path if the comment is in a thread of
+// the novel, otherwise return the status code to response with
+func getNovelAndComment(c *fiber.Ctx, db model.DB) (model.Novel, model.Comment, int) {
+	novel, commentID, status := getCommentTarget(c, db, "commentID")
+	if status != fiber.StatusOK {
+		return model.Novel{}, model.Comment{}, status
+	}
+	comment, ok := db.GetComment(commentID)
+	if !ok {
+		return model.Novel{}, model.Comment{}, fiber.StatusNotFound
+	}
+
+	return novel, comment, fiber.StatusOK
+}
+
+func checkCommentMetadata(input *model.CommentMetadata) (bool, ErrorCode) {
+	if len(input.Content) == 0 {
+		return false, BadInput
+	}
+
+	if utf8.RuneCountInString(input.Content) > model.CommentMaxLength {
+		return false, CommentTooLong
+	}
+
+	return true, BadInput
+}
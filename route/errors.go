@@ -30,6 +30,73 @@ const (
 	TitleTooLong
 	TaglineTooLong
 	DescriptionTooLong
+	ContentTooLong
+	BadOrder
+
+	// Account deletion related error
+	BadNovelAction
+
+	// Rating related error
+	BadRating
+	SelfRating
+
+	// Review related error
+	ReviewTooLong
+	ReplyTooLong
+	ReviewAlreadyExists
+
+	// Comment related error
+	CommentTooLong
+
+	// Follow related error
+	SelfFollow
+
+	// Tag related error
+	BadTagName
+	TagDescriptionTooLong
+	TagAlreadyExists
+	BadTag
+
+	// Image related error
+	BadImage
+	ImageTooLarge
+
+	// Report related error
+	BadReportReason
+	ReportDetailsTooLong
+	ReportAlreadyExists
+	BadReportAction
+	ReportOutcomeTooLong
+
+	// Role related error
+	BadRole
+	UserBanned
+
+	// Block related error
+	SelfBlock
+	BlockedByUser
+
+	// Reading progress related error
+	BadScrollOffset
+
+	// Bookmark related error
+	BadParagraph
+	BookmarkNoteTooLong
+
+	// Shelf related error
+	BadShelfName
+	ShelfAlreadyExists
+
+	// Email related error
+	BadToken
+	NoEmail
+
+	// Two-factor authentication related error
+	TwoFactorRequired
+	WrongTwoFactorCode
+	TwoFactorAlreadyEnabled
+	TwoFactorNotEnabled
+	TwoFactorLocked
 )
 
 var message = [...]string{
@@ -71,6 +138,90 @@ var message = [...]string{
 		"Description too long, description must contains less than %v letters",
 		model.DescriptionMaxLength,
 	),
+	fmt.Sprintf(
+		"Content too long, content must contains less than %v bytes",
+		model.ContentMaxLength,
+	),
+	"Bad order, the list must contain every item of the parent exactly once",
+	"Bad novel action, novel action must be either delete or transfer",
+	fmt.Sprintf(
+		"Bad rating, rating must be from %v to %v stars",
+		model.RatingMin,
+		model.RatingMax,
+	),
+	"The author cannot rate their own novel",
+	fmt.Sprintf(
+		"Review too long, review must contains less than %v letters",
+		model.ReviewMaxLength,
+	),
+	fmt.Sprintf(
+		"Reply too long, reply must contains less than %v letters",
+		model.ReviewReplyMaxLength,
+	),
+	"The user already reviewed this novel, consider updating the review instead",
+	fmt.Sprintf(
+		"Comment too long, comment must contains less than %v letters",
+		model.CommentMaxLength,
+	),
+	"The user cannot follow themselves",
+	fmt.Sprintf(
+		"Bad tag name, tag name must contains more than %v letters and less than %v letters",
+		model.TagNameMinLength,
+		model.TagNameMaxLength,
+	),
+	fmt.Sprintf(
+		"Tag description too long, tag description must contains less than %v letters",
+		model.TagDescriptionMaxLength,
+	),
+	"Tag already exists, consider using the existing tag or merging into it",
+	fmt.Sprintf(
+		"Bad tags, every tag must exist and a novel can have at most %v tags",
+		model.NovelMaxTags,
+	),
+	"Bad image, image must be a JPEG, PNG or GIF",
+	fmt.Sprintf(
+		"Image too large, image must be smaller than %vMB and %vx%v pixels",
+		model.ImageMaxSize>>20,
+		model.ImageMaxDimension,
+		model.ImageMaxDimension,
+	),
+	"Bad report reason, the reason must be one of the report reasons",
+	fmt.Sprintf(
+		"Report details too long, report details must contains less than %v letters",
+		model.ReportDetailsMaxLength,
+	),
+	"The user already reported this, the report is waiting for a moderator",
+	"Bad report action, report action must be either resolve, dismiss or action",
+	fmt.Sprintf(
+		"Report outcome too long, report outcome must contains less than %v letters",
+		model.ReportOutcomeMaxLength,
+	),
+	"Bad role, role must be either reader, author, moderator or admin",
+	"The user is banned",
+	"The user cannot block themselves",
+	"The user blocked you",
+	"Bad scroll offset, scroll offset must be from 0 to 1",
+	"Bad paragraph, paragraph must be the index of a paragraph of the chapter",
+	fmt.Sprintf(
+		"Bookmark note too long, bookmark note must contains less than %v letters",
+		model.BookmarkNoteMaxLength,
+	),
+	fmt.Sprintf(
+		"Bad shelf name, shelf name must contains more than %v letters and less than %v letters",
+		model.ShelfNameMinLength,
+		model.ShelfNameMaxLength,
+	),
+	"Shelf already exists, the user already has a shelf with this name",
+	"The token is invalid, expired or already used",
+	"The user has no email, set an email first",
+	"A code of the authenticator app or a recovery code is required",
+	"Wrong code, the code is invalid or was already used",
+	"The two-factor authentication is already enabled",
+	"The two-factor authentication is not enabled",
+	fmt.Sprintf(
+		"Too many wrong codes, try again in %v minutes",
+		model.TwoFactorLockDuration.Minutes(),
+	),
 }
 
 func getMessage(code ErrorCode) string {
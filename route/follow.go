@@ -0,0 +1,264 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+func addUserFollowRoutes(accountRoute fiber.Router, db model.DB) {
+	accountRoute.Get("/:username/followers", getUserFollowers(db))
+
+	accountRoute.Post("/:username/follow", followUser(db))
+
+	accountRoute.Delete("/:username/follow", unfollowUser(db))
+}
+
+func addNovelFollowRoutes(novelRoute fiber.Router, db model.DB) {
+	addReadRoute(novelRoute, "/:novelID/followers", getNovelFollowers(db))
+	novelRoute.Post("/:novelID/follow", followNovel(db))
+
+	novelRoute.Delete("/:novelID/follow", unfollowNovel(db))
+}
+
+// Follow User
+//
+//	@Summary		Follow the user with provided username
+//	@Description	Following twice has no effect, possible error code: SelfFollow, BlockedByUser
+//	@Tags			follow
+//	@Accept			json
+//	@Param			username		path	string						true	"Username"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/accounts/:username/follow [POST]
+func followUser(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if bytes.Compare(session.UserID, user.ID) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(SelfFollow))
+		}
+		if db.IsBlocking(user.ID, session.UserID) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BlockedByUser))
+		}
+
+		ok = db.FollowUser(session.UserID, user.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Unfollow User
+//
+//	@Summary	Unfollow the user with provided username
+//	@Tags		follow
+//	@Accept		json
+//	@Param		username		path	string						true	"Username"
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/accounts/:username/follow [DELETE]
+func unfollowUser(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		ok = db.UnfollowUser(session.UserID, user.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Get User's Followers
+//
+//	@Summary	Get the users following the user with provided username
+//	@Tags		follow
+//	@Produce	json
+//	@Param		username	path		string	true	"Username"
+//	@Param		page		query		uint	false	"Page"
+//	@Success	200			{object}	[]model.UserMetadataSmall
+//	@Failure	404
+//	@Failure	500
+//	@Router		/accounts/:username/followers [GET]
+func getUserFollowers(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page := c.QueryInt(QueryPage, 1)
+		pageUint := uint(page)
+		if page < 1 {
+			pageUint = 1
+		}
+
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		return c.JSON(db.GetUserFollowers(user.ID, pageUint))
+	}
+}
+
+// Follow Novel
+//
+//	@Summary		Follow the novel with provided novel id
+//	@Description	Following twice has no effect, private novels cannot be followed. Possible error code: BlockedByUser
+//	@Tags			follow
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/follow [POST]
+func followNovel(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if novel.Visibility == model.VisibilityPrivate {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		if db.IsBlocking(novel.Author, session.UserID) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BlockedByUser))
+		}
+
+		ok = db.FollowNovel(session.UserID, novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Unfollow Novel
+//
+//	@Summary	Unfollow the novel with provided novel id
+//	@Tags		follow
+//	@Accept		json
+//	@Param		NovelID			path	string						true	"Novel ID"
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/novel/:novelID/follow [DELETE]
+func unfollowNovel(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		ok = db.UnfollowNovel(session.UserID, novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Get Novel's Followers
+//
+//	@Summary		Get the users following the novel with provided novel id
+//	@Description	If the novel is private, the user need to be logged in with the author account
+//	@Tags			follow
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			page			query		uint						false	"Page"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	[]model.UserMetadataSmall
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/followers [GET]
+func getNovelFollowers(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if novel.Visibility == model.VisibilityPrivate && !isSessionUser(c, novel.Author) {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		page := c.QueryInt(QueryPage, 1)
+		pageUint := uint(page)
+		if page < 1 {
+			pageUint = 1
+		}
+
+		return c.JSON(db.GetNovelFollowers(novelID, pageUint))
+	}
+}
@@ -0,0 +1,219 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"time"
+)
+
+func addUserHistoryRoutes(accountRoute fiber.Router, db model.DB) {
+	addReadRoute(accountRoute, "/history", getHistory(db))
+	addReadRoute(accountRoute, "/history/settings", getHistorySettings(db))
+
+	accountRoute.Patch("/history/settings", updateHistorySettings(db))
+
+	accountRoute.Delete("/history/all", clearHistory(db))
+	accountRoute.Delete("/history/:entryID", deleteHistoryEntry(db))
+}
+
+// Get Reading History
+//
+//	@Summary		Get a page of the chapters the user opened, the newest first
+//	@Description	The dates are in the YYYY-MM-DD format and both ends of the range are included, a missing or bad date leaves that end open
+//	@Tags			history
+//	@Accept			json
+//	@Produce		json
+//	@Param			page			query		uint						false	"Page"
+//	@Param			from			query		string						false	"First day of the range"
+//	@Param			to				query		string						false	"Last day of the range"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		200				{object}	[]model.HistoryEntryView
+//	@Failure		401
+//	@Failure		500
+//	@Router			/accounts/history [GET]
+func getHistory(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		page := c.QueryInt(QueryPage, 1)
+		pageUint := uint(page)
+		if page < 1 {
+			pageUint = 1
+		}
+		from, to := getDateRange(c)
+
+		return c.JSON(db.GetHistory(session.UserID, from, to, pageUint))
+	}
+}
+
+// Get Reading History Settings
+//
+//	@Summary	Get whether the chapters the user opens are recorded
+//	@Tags		history
+//	@Accept		json
+//	@Produce	json
+//	@Param		sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success	200				{object}	model.HistorySettings
+//	@Failure	401
+//	@Failure	500
+//	@Router		/accounts/history/settings [GET]
+func getHistorySettings(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUserByID(session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.JSON(model.HistorySettings{Enabled: user.HistoryEnabled})
+	}
+}
+
+// Update Reading History Settings
+//
+//	@Summary		Enable or disable the recording of the chapters the user opens
+//	@Description	Disabling the history keeps the recorded entries, clear the history to remove them
+//	@Tags			history
+//	@Accept			json
+//	@Param			settings		body	model.HistorySettings		true	"Settings"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		500
+//	@Router			/accounts/history/settings [PATCH]
+func updateHistorySettings(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.HistorySettings
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		ok = db.SetHistoryEnabled(session.UserID, input.Enabled)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Delete Reading History Entry
+//
+//	@Summary	Remove the entry with provided entry id from the user's reading history
+//	@Tags		history
+//	@Accept		json
+//	@Param		EntryID			path	string						true	"Entry ID"
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/accounts/history/:entryID [DELETE]
+func deleteHistoryEntry(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		entryID, ok := getIDParam(c, "entryID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		entry, ok := db.GetHistoryEntry(entryID)
+		if !ok || !bytes.Equal(entry.UserID, session.UserID) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		ok = db.DeleteHistoryEntry(entryID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Clear Reading History
+//
+//	@Summary	Remove every entry from the user's reading history
+//	@Tags		history
+//	@Accept		json
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	500
+//	@Router		/accounts/history/all [DELETE]
+func clearHistory(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		ok = db.ClearHistory(session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Return the range of the from and to date queries, the to date is moved to the
+// start of the next day so the whole day is included. A missing or bad date is zero
+func getDateRange(c *fiber.Ctx) (time.Time, time.Time) {
+	from, err := time.Parse(time.DateOnly, c.Query(QueryFromDate, ""))
+	if err != nil {
+		from = time.Time{}
+	}
+	to, err := time.Parse(time.DateOnly, c.Query(QueryToDate, ""))
+	if err != nil {
+		to = time.Time{}
+	} else {
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to
+}
@@ -0,0 +1,263 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"Lightnovel/storage"
+	"Lightnovel/thumbnail"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const storageTimeout = time.Minute
+
+// The accepted image types and the file extension they are stored with
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Return the storage keys the uploaded image with the name can have, one for
+// each accepted extension
+func imageKeyCandidates(name string) []string {
+	keys := make([]string, 0, len(imageExtensions))
+	for _, extension := range imageExtensions {
+		keys = append(keys, name+extension)
+	}
+	return keys
+}
+
+func AddImageRoutes(router *fiber.Router, db model.DB, store storage.Storage, worker *thumbnail.Worker) {
+	imageRoute := (*router).Group("/image")
+
+	imageRoute.Get("/:key", getImage(db, store))
+
+	imageRoute.Post("/upload", uploadImage(db, store, worker))
+}
+
+type uploadImageResult struct {
+	ID  int    `json:"id"`
+	Url string `json:"url"`
+}
+
+// Upload Image
+//
+//	@Summary		Upload an image as multipart form data, return the url the image is served at
+//	@Description	The image must be a JPEG, PNG or GIF of at most 3MB, its EXIF and other metadata are removed. Use the returned url as the image of the user or the novel, the small, medium and large variants are generated in the background. Possible error code: BadInput, BadImage, ImageTooLarge
+//	@Tags			image
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			image	formData	file	true	"Image"
+//	@Param			novelId	formData	string	false	"The novel the image is for, the user must be its author"
+//	@Param			session	formData	string	true	"User's Session"
+//	@Success		201		{object}	uploadImageResult
+//	@Failure		400		{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/image/upload [POST]
+func uploadImage(db model.DB, store storage.Storage, worker *thumbnail.Worker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		var novelID []byte
+		if novelIDStr := c.FormValue("novelId"); novelIDStr != "" {
+			if len(novelIDStr) != model.IDHexLength {
+				return c.SendStatus(fiber.StatusNotFound)
+			}
+			id, err := Unhex(novelIDStr)
+			if err != nil {
+				return c.SendStatus(fiber.StatusNotFound)
+			}
+			novel, ok := db.GetNovel(id)
+			if !ok {
+				return c.SendStatus(fiber.StatusNotFound)
+			}
+			if bytes.Compare(session.UserID, novel.Author) != 0 {
+				return c.SendStatus(fiber.StatusUnauthorized)
+			}
+			novelID = id
+		}
+
+		fileHeader, err := c.FormFile("image")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if fileHeader.Size > model.ImageMaxSize {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(ImageTooLarge))
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		data, err := io.ReadAll(io.LimitReader(file, model.ImageMaxSize+1))
+		_ = file.Close()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if len(data) > model.ImageMaxSize {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(ImageTooLarge))
+		}
+
+		contentType, ok, code := checkImage(data)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+		data, err = thumbnail.StripMetadata(data, contentType)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadImage))
+		}
+
+		name := make([]byte, model.IDBinLength)
+		if _, err := rand.Read(name); err != nil {
+			log.Error(err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		key := hex.EncodeToString(name) + imageExtensions[contentType]
+		ctx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+		defer cancel()
+		if err := store.Save(ctx, key, contentType, data); err != nil {
+			log.Error(err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		url := model.ImageURLPath + key
+		imageID, ok := db.CreateImage(session.UserID, novelID, key, contentType, len(data), url)
+		if !ok {
+			if err := store.Delete(ctx, key); err != nil {
+				log.Error(err)
+			}
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		// The pending images are queued again periodically if the queue is full
+		worker.Enqueue(key)
+
+		return c.Status(fiber.StatusCreated).JSON(
+			uploadImageResult{
+				ID:  imageID,
+				Url: url,
+			})
+	}
+}
+
+// Get Image
+//
+//	@Summary		Get the uploaded image or one of its variants with provided key
+//	@Description	The response can be cached forever, the image behind a key never changes. The original image is served for a variant which is not generated yet, with a short cache
+//	@Tags			image
+//	@Produce		jpeg,png,gif
+//	@Param			key	path	string	true	"Image key"
+//	@Success		200
+//	@Failure		404
+//	@Failure		500
+//	@Router			/image/:key [GET]
+func getImage(db model.DB, store storage.Storage) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Params("key")
+		if !storage.IsKeyValid(key) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		contentType := ""
+		cacheControl := "public, max-age=31536000, immutable"
+		if name, _, isVariant := model.ParseImageVariantKey(key); isVariant {
+			img, ok := db.GetImageByKeys(imageKeyCandidates(name))
+			if !ok || !img.StorageKey.Valid {
+				return c.SendStatus(fiber.StatusNotFound)
+			}
+			contentType = "image/jpeg"
+			if !img.VariantsReady {
+				key = img.StorageKey.String
+				contentType = img.ContentType
+				cacheControl = "public, max-age=60"
+			}
+		} else {
+			img, ok := db.GetImageByKey(key)
+			if !ok {
+				return c.SendStatus(fiber.StatusNotFound)
+			}
+			contentType = img.ContentType
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+		defer cancel()
+		reader, err := store.Open(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if err != nil {
+			log.Error(err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		data, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			log.Error(err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		c.Set(fiber.HeaderContentType, contentType)
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+		return c.Send(data)
+	}
+}
+
+// Return the content type of the image if it is an accepted image type
+// and its dimensions are within the limit
+func checkImage(data []byte) (string, bool, ErrorCode) {
+	contentType := http.DetectContentType(data)
+	if _, ok := imageExtensions[contentType]; !ok {
+		return "", false, BadImage
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !strings.HasSuffix(contentType, format) {
+		return "", false, BadImage
+	}
+	if config.Width > model.ImageMaxDimension || config.Height > model.ImageMaxDimension {
+		return "", false, ImageTooLarge
+	}
+
+	return contentType, true, BadInput
+}
+
+// Delete the files and the variants of the images which no longer have a record
+func deleteImageFiles(db model.DB, store storage.Storage, keys []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+	defer cancel()
+	for _, key := range keys {
+		if _, ok := db.GetImageByKey(key); ok {
+			continue
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			log.Error(err)
+		}
+		for _, variant := range model.ImageVariantList {
+			if err := store.Delete(ctx, variant.Key(key)); err != nil {
+				log.Error(err)
+			}
+		}
+	}
+}
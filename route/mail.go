@@ -0,0 +1,236 @@
+package route
+
+import (
+	"Lightnovel/mailer"
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// The length of the tokens sent by email once hex encoded
+const userTokenHexLength = 64
+
+func addUserMailRoutes(accountRoute fiber.Router, db model.DB, mail *mailer.Queue, appURL string) {
+	accountRoute.Post("/email/verify/send", sendEmailVerification(db, mail, appURL))
+	accountRoute.Post("/email/verify", verifyEmail(db))
+	accountRoute.Post("/password/reset/send", sendPasswordReset(db, mail, appURL))
+	accountRoute.Post("/password/reset", resetPassword(db))
+}
+
+type userTokenInput struct {
+	Token string `json:"token"`
+}
+
+type passwordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type passwordResetInput struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+func isUserTokenValid(token string) bool {
+	if len(token) != userTokenHexLength {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
+
+func emailVerificationMessage(to string, appURL string, token string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Open the link below to verify your email, the link expires in %v.\n\n%v/verify-email?token=%v\n\n"+
+				"If you did not add this email to your account, ignore this message.\n",
+			model.TokenVerifyEmail.Duration(),
+			appURL,
+			token,
+		),
+	}
+}
+
+func passwordResetMessage(to string, appURL string, token string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Open the link below to choose a new password, the link expires in %v.\n\n%v/reset-password?token=%v\n\n"+
+				"If you did not ask to reset your password, ignore this message.\n",
+			model.TokenResetPassword.Duration(),
+			appURL,
+			token,
+		),
+	}
+}
+
+// Send Email Verification
+//
+//	@Summary		Send a link to verify the email of the user
+//	@Description	The email is sent in the background and the link of the previous email stops working. Nothing is sent within 5 minutes of the previous link. Possible error: NoEmail
+//	@Tags			accounts
+//	@Accept			json
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		500
+//	@Failure		503
+//	@Router			/accounts/email/verify/send [POST]
+func sendEmailVerification(db model.DB, mail *mailer.Queue, appURL string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUserByID(session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if !user.Email.Valid || user.Email.String == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(NoEmail))
+		}
+		if user.EmailVerifiedAt.Valid {
+			return c.SendStatus(fiber.StatusOK)
+		}
+
+		userID, email := user.ID, user.Email.String
+		queued := mail.Enqueue(func() (mailer.Message, bool) {
+			token, ok := db.CreateUserToken(userID, model.TokenVerifyEmail, email)
+			if !ok {
+				return mailer.Message{}, false
+			}
+			return emailVerificationMessage(email, appURL, token), true
+		})
+		if !queued {
+			return c.SendStatus(fiber.StatusServiceUnavailable)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Verify Email
+//
+//	@Summary		Verify the email with the token of the link sent to it
+//	@Description	Possible error: BadInput, BadToken
+//	@Tags			accounts
+//	@Accept			json
+//	@Param			token	body	userTokenInput	true	"Token"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Router			/accounts/email/verify [POST]
+func verifyEmail(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input userTokenInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if !isUserTokenValid(input.Token) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadToken))
+		}
+
+		userToken, ok := db.ConsumeUserToken(input.Token, model.TokenVerifyEmail)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadToken))
+		}
+		// The email was changed after the link was sent
+		if !db.VerifyUserEmail(userToken.UserID, userToken.Email) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadToken))
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Send Password Reset
+//
+//	@Summary		Send a link to reset the password to the verified email
+//	@Description	The answer is the same whether an account has the email or not, so the emails of the users are not revealed. Nothing is sent within 5 minutes of the previous link. Possible error: BadInput, BadEmail
+//	@Tags			accounts
+//	@Accept			json
+//	@Param			email	body	passwordResetRequest	true	"Email"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Router			/accounts/password/reset/send [POST]
+func sendPasswordReset(db model.DB, mail *mailer.Queue, appURL string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input passwordResetRequest
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if input.Email == "" || len(input.Email) > model.EmailMaxLength {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadEmail))
+		}
+
+		// Sent in the background so the response time does not tell whether the email exists
+		email := input.Email
+		queued := mail.Enqueue(func() (mailer.Message, bool) {
+			user, ok := db.GetUserByEmail(email)
+			if !ok || !user.EmailVerifiedAt.Valid || user.BannedAt.Valid {
+				return mailer.Message{}, false
+			}
+			token, ok := db.CreateUserToken(user.ID, model.TokenResetPassword, email)
+			if !ok {
+				return mailer.Message{}, false
+			}
+			return passwordResetMessage(email, appURL, token), true
+		})
+		if !queued {
+			log.Warn("The mail queue is full, password reset email dropped")
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Reset Password
+//
+//	@Summary		Set a new password with the token of the link sent by email
+//	@Description	Every session of the user is ended. The link stops working once the email of the user changes. Possible error: BadInput, BadPassword, BadToken
+//	@Tags			accounts
+//	@Accept			json
+//	@Param			input	body	passwordResetInput	true	"Token and new password"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		500
+//	@Router			/accounts/password/reset [POST]
+func resetPassword(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input passwordResetInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if !IsPasswordValid(input.NewPassword) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadPassword))
+		}
+		if !isUserTokenValid(input.Token) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadToken))
+		}
+
+		newHashed, err := PasswordHash(input.NewPassword)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadPassword))
+		}
+
+		if !db.ResetPassword(input.Token, newHashed) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadToken))
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
@@ -11,19 +11,31 @@ import (
 	"unicode/utf8"
 )
 
-// TODO: Delete novel
 func AddUploadRoutes(router *fiber.Router, db model.DB) {
 	novelRoute := (*router).Group("/novel")
 
 	novelRoute.Get("/find", searchAndFilterNovel(db))
 
 	novelRoute.Post("/create", createNovel(db))
-	novelRoute.Post("/from/:username", getUsersNovels(db))
-	novelRoute.Post("/:novelID", getNovel(db))
+	addReadRoute(novelRoute, "/from/:username", getUsersNovels(db))
+	addReadRoute(novelRoute, "/:novelID", getNovel(db))
+	addReadRoute(novelRoute, "/:novelID/toc", getNovelTOC(db))
+	novelRoute.Post("/:novelID/restore", restoreNovel(db))
 
 	novelRoute.Patch("/:novelID", updateNovelMetadata(db))
 
 	novelRoute.Delete("/:novelID", deleteNovel(db))
+
+	addVolumeRoutes(novelRoute, db)
+	addChapterRoutes(novelRoute, db)
+	addRatingRoutes(novelRoute, db)
+	addReviewRoutes(novelRoute, db)
+	addCommentRoutes(novelRoute, db)
+	addNovelFollowRoutes(novelRoute, db)
+	addNovelTagRoutes(novelRoute, db)
+	addNovelReportRoutes(novelRoute, db)
+	addProgressRoutes(novelRoute, db)
+	addBookmarkRoutes(novelRoute, db)
 }
 
 // Get Novel
@@ -37,7 +49,7 @@ func AddUploadRoutes(router *fiber.Router, db model.DB) {
 //	@Success		200				{object}	model.NovelView
 //	@Failure		404
 //	@Failure		500
-//	@Router			/novel/:novelID [POST]
+//	@Router			/novel/:novelID [GET]
 func getNovel(db model.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		novelIDStr := c.Params("novelID")
@@ -72,11 +84,55 @@ func getNovel(db model.DB) fiber.Handler {
 			}
 		}
 
+		if c.Locals(middleware.KeyIsUserAuth) == true {
+			session, _ := c.Locals(middleware.KeyUserSession).(model.Session)
+			novelView.MyRating = db.GetUserRating(session.UserID, novelID)
+			novelView.IsFollowing = db.IsFollowingNovel(session.UserID, novelID)
+		}
+
 		// Everything is good
 		return c.JSON(novelView)
 	}
 }
 
+// Get Novel's Table of Contents
+//
+//	@Summary		Get the volumes of the novel with provided novel id along with their chapters in reading order
+//	@Description	Private volumes and chapters are only returned to the author, if the novel is private, the user need to be logged in with the author account
+//	@Tags			novel
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	model.NovelTOC
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/toc [GET]
+func getNovelTOC(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		isAuthor := isSessionUser(c, novel.Author)
+		if novel.Visibility == model.VisibilityPrivate && !isAuthor {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		toc, ok := db.GetNovelTOC(novelID, isAuthor)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return c.JSON(toc)
+	}
+}
+
 type createNovelResult struct {
 	NovelID string `json:"novel_id"`
 }
@@ -84,7 +140,7 @@ type createNovelResult struct {
 // Create Novel
 //
 //	@Summary		Create a new novel with the provided metadata, return the created novel id
-//	@Description	Possible error code: MissingField, InvalidLanguageFormat, TitleTooLong, TaglineTooLong
+//	@Description	Possible error code: MissingField, InvalidLanguageFormat, TitleTooLong, TaglineTooLong, BadTag
 //	@Tags			novel
 //	@Accept			json
 //	@Produce		json
@@ -109,6 +165,9 @@ func createNovel(db model.DB) fiber.Handler {
 		if ok, code := checkNovelMetadata(&input); !ok {
 			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
 		}
+		if ok, code := checkTagIDs(db, input.Tags); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
 
 		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
 		if !ok {
@@ -131,7 +190,7 @@ func createNovel(db model.DB) fiber.Handler {
 // Update Novel Metadata
 //
 //	@Summary		Update the novel metadata with the provided metadata
-//	@Description	Possible error code: MissingField, InvalidLanguageFormat, TitleTooLong, TaglineTooLong
+//	@Description	Only the author of the novel can update it, the tags are left unchanged if omitted. Possible error code: MissingField, InvalidLanguageFormat, TitleTooLong, TaglineTooLong, BadTag
 //	@Tags			novel
 //	@Accept			json
 //	@Param			NovelID			path	string						true	"Novel ID"
@@ -158,6 +217,11 @@ func updateNovelMetadata(db model.DB) fiber.Handler {
 		if ok, code := checkNovelMetadata(&input); !ok {
 			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
 		}
+		if input.Tags != nil {
+			if ok, code := checkTagIDs(db, input.Tags); !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+			}
+		}
 
 		novelIDStr := c.Params("novelID")
 		if len(novelIDStr) != model.IDHexLength {
@@ -173,7 +237,11 @@ func updateNovelMetadata(db model.DB) fiber.Handler {
 			log.Warn("Check the authentication middleware")
 			return c.SendStatus(fiber.StatusInternalServerError)
 		}
-		if bytes.Compare(session.UserID, input.Author) != 0 {
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
 			return c.SendStatus(fiber.StatusUnauthorized)
 		}
 
@@ -199,7 +267,7 @@ func updateNovelMetadata(db model.DB) fiber.Handler {
 //	@Failure		401
 //	@Failure		404
 //	@Failure		500
-//	@Router			/novel/from/:username [POST]
+//	@Router			/novel/from/:username [GET]
 func getUsersNovels(db model.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		username := c.Params("username")
@@ -225,7 +293,7 @@ func getUsersNovels(db model.DB) fiber.Handler {
 
 // Search and Filter Novels
 //
-//	@Summary	Search and filter novels with the provided filters and sorting options, if no filters and sorting options are provided, all the public novels will be returned
+//	@Summary	Search and filter novels with the provided filters and sorting options, if no filters and sorting options are provided, all the public novels will be returned. The novels of the users the logged in user blocked are left out
 //	@Tags		novel
 //	@Produce	json
 //	@Param		filtersAndSort	query		model.FiltersAndSortNovel	false	"Filters and sorting options"
@@ -235,6 +303,7 @@ func getUsersNovels(db model.DB) fiber.Handler {
 func searchAndFilterNovel(db model.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		filtersAndSortOption := getFiltersAndSort(c)
+		filtersAndSortOption.ViewerID = getViewerID(c)
 
 		novels := db.FindNovels(&filtersAndSortOption)
 		return c.JSON(novels)
@@ -243,48 +312,97 @@ func searchAndFilterNovel(db model.DB) fiber.Handler {
 
 // Delete Novel
 //
-//	@Deprecated
-//	@Summary	Delete the novel and all the related stuff like volumes, chapters, comments, images with the provided novel id
-//	@Tags		novel
-//	@Param		NovelID	path	string	true	"Novel ID"
-//	@Success	200
-//	@Failure	401
-//	@Failure	404
-//	@Failure	500
-//	@Router		/novel/:novelID [DELETE]
+//	@Summary		Delete the novel and all the related stuff like volumes, chapters, comments, images with the provided novel id
+//	@Description	The novel is hidden right away and can be restored within 7 days, after that it is permanently deleted
+//	@Tags			novel
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID [DELETE]
 func deleteNovel(db model.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		return c.SendStatus(fiber.StatusNotImplemented)
-		//novelID := c.Params("novelID")
-		//if len(novelID) != model.IDHexLength {
-		//	return c.SendStatus(fiber.StatusNotFound)
-		//}
-		//
-		//if c.Locals(middleware.KeyIsUserAuth) == false {
-		//	return c.SendStatus(fiber.StatusUnauthorized)
-		//}
-		//
-		//session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
-		//if !ok {
-		//	log.Warn("Check the authentication middleware")
-		//	return c.SendStatus(fiber.StatusInternalServerError)
-		//}
-		//
-		//novel, ok := db.GetNovel(novelID)
-		//if !ok {
-		//	return c.SendStatus(fiber.StatusNotFound)
-		//}
-		//
-		//if bytes.Compare(session.UserID, novel.Author) != 0 {
-		//	return c.SendStatus(fiber.StatusUnauthorized)
-		//}
-		//
-		//ok = db.DeleteNovel(novelID)
-		//if !ok {
-		//	return c.SendStatus(fiber.StatusInternalServerError)
-		//}
-		//
-		//return c.SendStatus(fiber.StatusOK)
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.DeleteNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Restore Novel
+//
+//	@Summary		Restore the deleted novel with the provided novel id
+//	@Description	Only novels deleted within the last 7 days can be restored, a novel removed by a moderator cannot be restored
+//	@Tags			novel
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/restore [POST]
+func restoreNovel(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, ok := db.GetDeletedNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.RestoreNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
 	}
 }
 
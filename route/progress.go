@@ -0,0 +1,256 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+func addProgressRoutes(novelRoute fiber.Router, db model.DB) {
+	addReadRoute(novelRoute, "/:novelID/progress", getNovelProgress(db))
+	novelRoute.Post("/:novelID/chapter/:chapterID/read", markChapterRead(db))
+
+	novelRoute.Patch("/:novelID/chapter/:chapterID/progress", saveProgress(db))
+
+	novelRoute.Delete("/:novelID/chapter/:chapterID/read", unmarkChapterRead(db))
+}
+
+func addContinueReadingRoutes(accountRoute fiber.Router, db model.DB) {
+	addReadRoute(accountRoute, "/continue", getContinueReading(db))
+}
+
+// Save Reading Progress
+//
+//	@Summary		Record the chapter and the scroll offset the user is at in the novel
+//	@Description	The progress replaces the previous progress on the novel and moves the novel to the top of the continue reading list. Possible error code: BadInput, BadScrollOffset
+//	@Tags			progress
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			ChapterID		path	string						true	"Chapter ID"
+//	@Param			progress		body	model.ProgressMetadata		true	"Progress"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/chapter/:chapterID/progress [PATCH]
+func saveProgress(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.ProgressMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if input.ScrollOffset < 0 || input.ScrollOffset > 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadScrollOffset))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, chapter, status := getReadableChapter(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		ok = db.SaveReadingProgress(session.UserID, novel.ID, chapter.ID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Get Reading Progress
+//
+//	@Summary		Get where the user stopped reading the novel and the chapters marked as read
+//	@Description	The chapter id is empty if the user has not started reading the novel
+//	@Tags			progress
+//	@Accept			json
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		200				{object}	model.NovelProgressView
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/progress [GET]
+func getNovelProgress(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if novel.Visibility == model.VisibilityPrivate && !isSessionUser(c, novel.Author) {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		progressView := model.NovelProgressView{
+			NovelID:      hex.EncodeToString(novel.ID),
+			ReadChapters: db.GetReadChapterIDs(session.UserID, novel.ID),
+		}
+		if progress, ok := db.GetReadingProgress(session.UserID, novel.ID); ok {
+			progressView.ChapterID = hex.EncodeToString(progress.ChapterID)
+			progressView.ScrollOffset = progress.ScrollOffset
+			progressView.UpdateAt = progress.UpdateAt
+		}
+
+		return c.JSON(progressView)
+	}
+}
+
+// Get Continue Reading
+//
+//	@Summary	Get a page of the novels the user is reading with where they stopped, the most recently read first
+//	@Tags		progress
+//	@Accept		json
+//	@Produce	json
+//	@Param		page			query		uint						false	"Page"
+//	@Param		sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success	200				{object}	[]model.ReadingProgressView
+//	@Failure	401
+//	@Failure	500
+//	@Router		/accounts/continue [GET]
+func getContinueReading(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		page := c.QueryInt(QueryPage, 1)
+		pageUint := uint(page)
+		if page < 1 {
+			pageUint = 1
+		}
+
+		return c.JSON(db.GetContinueReading(session.UserID, pageUint))
+	}
+}
+
+// Mark Chapter Read
+//
+//	@Summary	Mark the chapter as read by the user, marking twice has no effect
+//	@Tags		progress
+//	@Accept		json
+//	@Param		NovelID			path	string						true	"Novel ID"
+//	@Param		ChapterID		path	string						true	"Chapter ID"
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/novel/:novelID/chapter/:chapterID/read [POST]
+func markChapterRead(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, chapter, status := getReadableChapter(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		ok = db.MarkChapterRead(session.UserID, novel.ID, chapter.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Unmark Chapter Read
+//
+//	@Summary	Mark the chapter as not read by the user
+//	@Tags		progress
+//	@Accept		json
+//	@Param		NovelID			path	string						true	"Novel ID"
+//	@Param		ChapterID		path	string						true	"Chapter ID"
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/novel/:novelID/chapter/:chapterID/read [DELETE]
+func unmarkChapterRead(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		_, _, chapter, status := getNovelVolumeAndChapter(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		ok = db.UnmarkChapterRead(session.UserID, chapter.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Return the novel and the chapter from the path if the requesting user can read
+// the chapter, otherwise return the status code to response with
+func getReadableChapter(c *fiber.Ctx, db model.DB) (model.Novel, model.Chapter, int) {
+	novel, volume, chapter, status := getNovelVolumeAndChapter(c, db)
+	if status != fiber.StatusOK {
+		return model.Novel{}, model.Chapter{}, status
+	}
+
+	if novel.Visibility == model.VisibilityPrivate ||
+		volume.Visibility == model.VisibilityPrivate ||
+		chapter.Visibility == model.VisibilityPrivate {
+		if !isSessionUser(c, novel.Author) {
+			return model.Novel{}, model.Chapter{}, fiber.StatusUnauthorized
+		}
+	}
+
+	return novel, chapter, fiber.StatusOK
+}
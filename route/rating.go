@@ -0,0 +1,122 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+func addRatingRoutes(novelRoute fiber.Router, db model.DB) {
+	novelRoute.Post("/:novelID/rating", rateNovel(db))
+
+	novelRoute.Delete("/:novelID/rating", deleteRating(db))
+}
+
+type ratingInput struct {
+	Rating int `json:"rating"`
+}
+
+// Rate Novel
+//
+//	@Summary		Rate the novel with provided novel id, rating again changes the previous rating
+//	@Description	The rating must be from 1 to 5 stars, the author cannot rate their own novel. Possible error code: BadInput, BadRating, SelfRating
+//	@Tags			rating
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			rating			body	ratingInput					true	"Number of stars"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/rating [POST]
+func rateNovel(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input ratingInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if input.Rating < model.RatingMin || input.Rating > model.RatingMax {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadRating))
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if bytes.Compare(session.UserID, novel.Author) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(SelfRating))
+		}
+		if novel.Visibility == model.VisibilityPrivate {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.RateNovel(session.UserID, novelID, input.Rating)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Delete Rating
+//
+//	@Summary	Remove the user's rating of the novel with provided novel id along with the user's review of the novel
+//	@Tags		rating
+//	@Accept		json
+//	@Param		NovelID			path	string						true	"Novel ID"
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/novel/:novelID/rating [DELETE]
+func deleteRating(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		if _, ok := db.GetNovel(novelID); !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		ok = db.DeleteRating(session.UserID, novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
@@ -0,0 +1,307 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"strings"
+	"unicode/utf8"
+)
+
+func AddReportRoutes(router *fiber.Router, db model.DB) {
+	reportRoute := (*router).Group("/report")
+
+	reportRoute.Get("/reasons", getReportReasons(db))
+
+	moderate := middleware.RequirePermission(model.PermissionModerate)
+	addReadRoute(reportRoute, "/queue", moderate, getReportQueue(db))
+	addReadRoute(reportRoute, "/:targetID/history", moderate, getReportResolutions(db))
+	reportRoute.Post("/:targetID/resolve", moderate, resolveReports(db))
+}
+
+func addUserReportRoutes(accountRoute fiber.Router, db model.DB) {
+	accountRoute.Post("/:username/report", reportUser(db))
+}
+
+func addNovelReportRoutes(novelRoute fiber.Router, db model.DB) {
+	novelRoute.Post("/:novelID/report/:targetID", reportNovelContent(db))
+}
+
+// Get Report Reasons
+//
+//	@Summary	Get the reasons a report can be filed for
+//	@Tags		report
+//	@Produce	json
+//	@Success	200	{object}	[]model.ReportReason
+//	@Failure	500
+//	@Router		/report/reasons [GET]
+func getReportReasons(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(db.GetReportReasons())
+	}
+}
+
+// Report User
+//
+//	@Summary		Report the user with provided username to the moderators
+//	@Description	A user can have only one open report on the same target. Possible error code: BadInput, BadReportReason, ReportDetailsTooLong, ReportAlreadyExists
+//	@Tags			report
+//	@Accept			json
+//	@Param			username		path	string						true	"Username"
+//	@Param			report			body	model.ReportMetadata		true	"Report"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		201
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/accounts/:username/report [POST]
+func reportUser(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if bytes.Equal(session.UserID, user.ID) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		return fileReport(c, db, session.UserID, user.ID, model.ReportTargetUser)
+	}
+}
+
+// Report Novel Content
+//
+//	@Summary		Report the novel, one of its chapters or a comment in its threads to the moderators
+//	@Description	A user can have only one open report on the same target. If the novel or the chapter is private, the user need to be logged in with the author account. Possible error code: BadInput, BadReportReason, ReportDetailsTooLong, ReportAlreadyExists
+//	@Tags			report
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			TargetID		path	string						true	"Novel, Chapter or Comment ID"
+//	@Param			report			body	model.ReportMetadata		true	"Report"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		201
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/report/:targetID [POST]
+func reportNovelContent(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, targetID, status := getCommentTarget(c, db, "targetID")
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		var targetType model.ReportTarget
+		if bytes.Equal(targetID, novel.ID) {
+			targetType = model.ReportTargetNovel
+		} else if _, ok := db.GetComment(targetID); ok {
+			targetType = model.ReportTargetComment
+		} else if _, ok := db.GetChapter(targetID); ok {
+			targetType = model.ReportTargetChapter
+		} else {
+			// Volumes cannot be reported
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		return fileReport(c, db, session.UserID, targetID, targetType)
+	}
+}
+
+// Parse the report from the body and file it against the target
+func fileReport(
+	c *fiber.Ctx,
+	db model.DB,
+	userID []byte,
+	targetID []byte,
+	targetType model.ReportTarget,
+) error {
+	var input model.ReportMetadata
+	err := c.BodyParser(&input)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+	}
+	if ok, code := checkReportMetadata(db, &input); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+	}
+	if db.HasOpenReport(userID, targetID) {
+		return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(ReportAlreadyExists))
+	}
+
+	ok := db.CreateReport(userID, targetID, targetType, &input)
+	if !ok {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+// Get Report Queue
+//
+//	@Summary		Get a page of the targets with open reports, the most reported first
+//	@Description	The reports are grouped by target with the number of reports for each reason. Only users with the moderate permission can see the queue
+//	@Tags			report
+//	@Accept			json
+//	@Produce		json
+//	@Param			page			query		uint						false	"Page"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		200				{object}	[]model.ReportGroup
+//	@Failure		401
+//	@Failure		500
+//	@Router			/report/queue [GET]
+func getReportQueue(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page := c.QueryInt(QueryPage, 1)
+		pageUint := uint(page)
+		if page < 1 {
+			pageUint = 1
+		}
+
+		return c.JSON(db.GetReportQueue(pageUint))
+	}
+}
+
+// Get Report History
+//
+//	@Summary		Get the recorded outcomes of the reports on the target from the newest to the oldest
+//	@Description	Only users with the moderate permission can see the history
+//	@Tags			report
+//	@Accept			json
+//	@Produce		json
+//	@Param			TargetID		path		string						true	"User, Novel, Chapter or Comment ID"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		200				{object}	[]model.ReportResolutionView
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/report/:targetID/history [GET]
+func getReportResolutions(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		targetID, ok := getIDParam(c, "targetID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		return c.JSON(db.GetReportResolutions(targetID))
+	}
+}
+
+type resolveReportsInput struct {
+	Action  model.ReportAction `json:"action"`
+	Outcome string             `json:"outcome"`
+}
+
+// Resolve Reports
+//
+//	@Summary		Close every open report on the target and record the outcome
+//	@Description	The action is resolve, dismiss or action. Taking action removes the reported novel so that its author cannot restore it, deletes the reported chapter or comment, or bans the reported user if their role is below the moderator's, the closed reports are kept. The answer is 404 if the target has no open report, for example when another moderator resolved them first. Only users with the moderate permission can resolve reports. Possible error code: BadInput, BadReportAction, ReportOutcomeTooLong
+//	@Tags			report
+//	@Accept			json
+//	@Param			TargetID		path	string						true	"User, Novel, Chapter or Comment ID"
+//	@Param			resolution		body	resolveReportsInput			true	"Action and outcome"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/report/:targetID/resolve [POST]
+func resolveReports(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		var input resolveReportsInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkResolveReportsInput(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		targetID, ok := getIDParam(c, "targetID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		group, ok := db.GetReportGroup(targetID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		banUser := input.Action == model.ReportActionTakeAction &&
+			group.TargetType == model.ReportTargetUser
+		if banUser {
+			user, ok := db.GetUserByID(targetID)
+			if !ok {
+				return c.SendStatus(fiber.StatusNotFound)
+			}
+			if !canManageUser(c, user) {
+				return c.SendStatus(fiber.StatusUnauthorized)
+			}
+		}
+
+		// Another moderator may have resolved the reports in the meantime
+		found, ok := db.ResolveReports(targetID, session.UserID, input.Action, input.Outcome)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if !found {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+func checkReportMetadata(db model.DB, input *model.ReportMetadata) (bool, ErrorCode) {
+	input.Details = strings.TrimSpace(input.Details)
+	if utf8.RuneCountInString(input.Details) > model.ReportDetailsMaxLength {
+		return false, ReportDetailsTooLong
+	}
+
+	for _, reason := range db.GetReportReasons() {
+		if reason.ID == input.ReasonID {
+			return true, BadInput
+		}
+	}
+	return false, BadReportReason
+}
+
+func checkResolveReportsInput(input *resolveReportsInput) (bool, ErrorCode) {
+	if !input.Action.Validate() {
+		return false, BadReportAction
+	}
+
+	input.Outcome = strings.TrimSpace(input.Outcome)
+	if utf8.RuneCountInString(input.Outcome) > model.ReportOutcomeMaxLength {
+		return false, ReportOutcomeTooLong
+	}
+
+	return true, BadInput
+}
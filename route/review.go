@@ -0,0 +1,468 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"unicode/utf8"
+)
+
+func addReviewRoutes(novelRoute fiber.Router, db model.DB) {
+	addReadRoute(novelRoute, "/:novelID/review", getNovelReviews(db))
+	novelRoute.Post("/:novelID/review/create", createReview(db))
+	novelRoute.Post("/:novelID/review/:reviewID/helpful", voteReviewHelpful(db))
+	novelRoute.Post("/:novelID/review/:reviewID/reply", replyReview(db))
+
+	novelRoute.Patch("/:novelID/review/:reviewID", updateReview(db))
+
+	novelRoute.Delete("/:novelID/review/:reviewID", deleteReview(db))
+	novelRoute.Delete("/:novelID/review/:reviewID/helpful", unvoteReviewHelpful(db))
+	novelRoute.Delete("/:novelID/review/:reviewID/reply", deleteReviewReply(db))
+}
+
+// Get Novel's Reviews
+//
+//	@Summary		Get the reviews of the novel with provided novel id
+//	@Description	If the novel is private, the user need to be logged in with the author account
+//	@Tags			review
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			sort			query		string						false	"Sort by helpful or newest, default is helpful"
+//	@Param			page			query		uint						false	"Page"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	[]model.ReviewView
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/review [GET]
+func getNovelReviews(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if novel.Visibility == model.VisibilityPrivate && !isSessionUser(c, novel.Author) {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		sort := model.ReviewSort(c.Query(QueryReviewSort, ""))
+		if !sort.Validate() {
+			sort = model.ReviewSortHelpful
+		}
+		page := c.QueryInt(QueryPage, 1)
+		pageUint := uint(page)
+		if page < 1 {
+			pageUint = 1
+		}
+
+		return c.JSON(db.GetNovelReviews(novelID, sort, pageUint))
+	}
+}
+
+type createReviewResult struct {
+	ReviewID string `json:"review_id"`
+}
+
+// Create Review
+//
+//	@Summary		Write a review for the novel with provided novel id, return the created review id
+//	@Description	Each user can only write one review per novel, the rating in the review replaces the user's rating of the novel. Possible error code: BadInput, TitleTooLong, ReviewTooLong, BadRating, SelfRating, ReviewAlreadyExists
+//	@Tags			review
+//	@Accept			json
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			review			body		model.ReviewMetadata		true	"Review"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		201				{object}	createReviewResult
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/review/create [POST]
+func createReview(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.ReviewMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkReviewMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if bytes.Compare(session.UserID, novel.Author) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(SelfRating))
+		}
+		if novel.Visibility == model.VisibilityPrivate {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		if _, ok := db.GetUserReview(session.UserID, novelID); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(ReviewAlreadyExists))
+		}
+
+		uid, ok := db.CreateReview(session.UserID, novelID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(
+			createReviewResult{
+				ReviewID: hex.EncodeToString(uid),
+			})
+	}
+}
+
+// Update Review
+//
+//	@Summary		Update the user's review
+//	@Description	Only the reviewer can update the review, the rating in the review replaces the user's rating of the novel. Possible error code: BadInput, TitleTooLong, ReviewTooLong, BadRating
+//	@Tags			review
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			ReviewID		path	string						true	"Review ID"
+//	@Param			review			body	model.ReviewMetadata		true	"Review"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/review/:reviewID [PATCH]
+func updateReview(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.ReviewMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkReviewMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		_, review, status := getNovelAndReview(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if bytes.Compare(session.UserID, review.UserID) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.UpdateReview(review.ID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Delete Review
+//
+//	@Summary		Delete the user's review, the user's rating of the novel is kept
+//	@Description	Only the reviewer can delete the review
+//	@Tags			review
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			ReviewID		path	string						true	"Review ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/review/:reviewID [DELETE]
+func deleteReview(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		_, review, status := getNovelAndReview(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if bytes.Compare(session.UserID, review.UserID) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.DeleteReview(review.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Vote Review Helpful
+//
+//	@Summary		Mark the review as helpful
+//	@Description	Voting twice has no effect, the reviewer cannot vote their own review
+//	@Tags			review
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			ReviewID		path	string						true	"Review ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/review/:reviewID/helpful [POST]
+func voteReviewHelpful(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, review, status := getNovelAndReview(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if novel.Visibility == model.VisibilityPrivate {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		if bytes.Compare(session.UserID, review.UserID) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		ok = db.VoteReviewHelpful(review.ID, session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Unvote Review Helpful
+//
+//	@Summary	Remove the user's helpful vote of the review
+//	@Tags		review
+//	@Accept		json
+//	@Param		NovelID			path	string						true	"Novel ID"
+//	@Param		ReviewID		path	string						true	"Review ID"
+//	@Param		sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success	200
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/novel/:novelID/review/:reviewID/helpful [DELETE]
+func unvoteReviewHelpful(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		_, review, status := getNovelAndReview(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		ok = db.UnvoteReviewHelpful(review.ID, session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+type replyReviewInput struct {
+	Reply string `json:"reply"`
+}
+
+// Reply Review
+//
+//	@Summary		Reply to the review as the author of the novel
+//	@Description	Only the author of the novel can reply, replying again replaces the old reply. Possible error code: BadInput, ReplyTooLong
+//	@Tags			review
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			ReviewID		path	string						true	"Review ID"
+//	@Param			reply			body	replyReviewInput			true	"Reply"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/review/:reviewID/reply [POST]
+func replyReview(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input replyReviewInput
+		err := c.BodyParser(&input)
+		if err != nil || len(input.Reply) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if utf8.RuneCountInString(input.Reply) > model.ReviewReplyMaxLength {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(ReplyTooLong))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, review, status := getNovelAndReview(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.ReplyReview(review.ID, input.Reply)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Delete Review Reply
+//
+//	@Summary		Delete the author's reply to the review
+//	@Description	Only the author of the novel can delete the reply
+//	@Tags			review
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			ReviewID		path	string						true	"Review ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/review/:reviewID/reply [DELETE]
+func deleteReviewReply(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, review, status := getNovelAndReview(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.DeleteReviewReply(review.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Return the novel and the review from the path if the review belongs to the novel,
+// otherwise return the status code to response with
+func getNovelAndReview(c *fiber.Ctx, db model.DB) (model.Novel, model.Review, int) {
+	novelID, ok := getIDParam(c, "novelID")
+	if !ok {
+		return model.Novel{}, model.Review{}, fiber.StatusNotFound
+	}
+	reviewID, ok := getIDParam(c, "reviewID")
+	if !ok {
+		return model.Novel{}, model.Review{}, fiber.StatusNotFound
+	}
+
+	novel, ok := db.GetNovel(novelID)
+	if !ok {
+		return model.Novel{}, model.Review{}, fiber.StatusNotFound
+	}
+	review, ok := db.GetReview(reviewID)
+	if !ok || bytes.Compare(review.NovelID, novelID) != 0 {
+		return model.Novel{}, model.Review{}, fiber.StatusNotFound
+	}
+
+	return novel, review, fiber.StatusOK
+}
+
+func checkReviewMetadata(input *model.ReviewMetadata) (bool, ErrorCode) {
+	if len(input.Title) == 0 || len(input.Content) == 0 {
+		return false, BadInput
+	}
+
+	if utf8.RuneCountInString(input.Title) > model.TitleMaxLength {
+		return false, TitleTooLong
+	}
+
+	if utf8.RuneCountInString(input.Content) > model.ReviewMaxLength {
+		return false, ReviewTooLong
+	}
+
+	if input.Rating < model.RatingMin || input.Rating > model.RatingMax {
+		return false, BadRating
+	}
+
+	return true, BadInput
+}
@@ -0,0 +1,145 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+func addUserRoleRoutes(accountRoute fiber.Router, db model.DB) {
+	banUsers := middleware.RequirePermission(model.PermissionBanUsers)
+	accountRoute.Post("/:username/ban", banUsers, banUser(db))
+	accountRoute.Delete("/:username/ban", banUsers, unbanUser(db))
+
+	manageRoles := middleware.RequirePermission(model.PermissionManageRoles)
+	accountRoute.Patch("/:username/role", manageRoles, setUserRole(db))
+}
+
+// Ban User
+//
+//	@Summary		Ban the user with provided username and log them out of every device
+//	@Description	A banned user cannot log in. Only users with the ban_users permission can ban, and only users whose role is below their own
+//	@Tags			moderation
+//	@Accept			json
+//	@Param			username		path	string						true	"Username"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/accounts/:username/ban [POST]
+func banUser(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if !canManageUser(c, user) {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.BanUser(user.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Unban User
+//
+//	@Summary		Lift the ban of the user with provided username
+//	@Description	Only users with the ban_users permission can unban, and only users whose role is below their own
+//	@Tags			moderation
+//	@Accept			json
+//	@Param			username		path	string						true	"Username"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/accounts/:username/ban [DELETE]
+func unbanUser(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if !canManageUser(c, user) {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.UnbanUser(user.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+type setUserRoleInput struct {
+	Role model.Role `json:"role"`
+}
+
+// Set User's Role
+//
+//	@Summary		Change the role of the user with provided username
+//	@Description	The role is reader, author, moderator or admin. Only users with the manage_roles permission can change roles, and not their own. Possible error code: BadInput, BadRole
+//	@Tags			moderation
+//	@Accept			json
+//	@Param			username		path	string						true	"Username"
+//	@Param			role			body	setUserRoleInput			true	"Role"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/accounts/:username/role [PATCH]
+func setUserRole(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input setUserRoleInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if !input.Role.Validate() {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadRole))
+		}
+
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		// Keep at least the administrator who changes the roles
+		if isSessionUser(c, user.ID) {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.SetUserRole(user.ID, input.Role)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Check if the requesting user's role is above the user's role, must run after
+// middleware.RequirePermission
+func canManageUser(c *fiber.Ctx, user model.User) bool {
+	role, ok := c.Locals(middleware.KeyUserRole).(model.Role)
+	if !ok {
+		log.Warn("Check the permission middleware")
+		return false
+	}
+	session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+	if !ok || bytes.Equal(session.UserID, user.ID) {
+		return false
+	}
+	return role.Rank() > user.Role.Rank()
+}
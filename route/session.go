@@ -0,0 +1,119 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+func addUserSessionRoutes(accountRoute fiber.Router, db model.DB) {
+	addReadRoute(accountRoute, "/sessions", getSessions(db))
+
+	accountRoute.Delete("/sessions/others", revokeOtherSessions(db))
+	accountRoute.Delete("/sessions/:sessionID", revokeSession(db))
+}
+
+// Get Sessions
+//
+//	@Summary		Get the devices the user is logged in on, the most recently used first
+//	@Description	The last use and the IP address are updated when the session is renewed, so they can be behind by the lifetime of an access token
+//	@Tags			accounts
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		200				{object}	[]model.SessionView
+//	@Failure		401
+//	@Failure		500
+//	@Router			/accounts/sessions [GET]
+func getSessions(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.JSON(db.GetUserSessions(session.UserID, session.ID))
+	}
+}
+
+// Revoke Session
+//
+//	@Summary		Log out the device with provided session id
+//	@Description	The device keeps access until its access token expires
+//	@Tags			accounts
+//	@Accept			json
+//	@Param			SessionID		path	string						true	"Session ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/accounts/sessions/:sessionID [DELETE]
+func revokeSession(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		familyID, ok := getIDParam(c, "sessionID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		revoked, ok := db.GetSessionByFamily(familyID)
+		if !ok || !bytes.Equal(revoked.UserID, session.UserID) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		ok = db.DeleteSession(revoked.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Revoke Other Sessions
+//
+//	@Summary		Log out every device except the one making the request
+//	@Description	The other devices keep access until their access tokens expire
+//	@Tags			accounts
+//	@Accept			json
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		500
+//	@Router			/accounts/sessions/others [DELETE]
+func revokeOtherSessions(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		ok = db.DeleteOtherSessions(session.UserID, session.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
@@ -0,0 +1,456 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"strings"
+	"unicode/utf8"
+)
+
+func AddShelfRoutes(router *fiber.Router, db model.DB) {
+	shelfRoute := (*router).Group("/shelf")
+
+	shelfRoute.Get("/:shelfID", getShelfNovels(db))
+
+	shelfRoute.Post("/create", createShelf(db))
+	shelfRoute.Post("/:shelfID/novel/:novelID", addNovelToShelf(db))
+
+	shelfRoute.Patch("/order", reorderShelves(db))
+	shelfRoute.Patch("/:shelfID", updateShelf(db))
+	shelfRoute.Patch("/:shelfID/order", reorderShelfNovels(db))
+
+	shelfRoute.Delete("/:shelfID", deleteShelf(db))
+	shelfRoute.Delete("/:shelfID/novel/:novelID", removeNovelFromShelf(db))
+}
+
+func addUserShelfRoutes(accountRoute fiber.Router, db model.DB) {
+	accountRoute.Get("/:username/shelves", getUserShelves(db))
+}
+
+type createShelfResult struct {
+	ShelfID string `json:"shelfId"`
+}
+
+// Create Shelf
+//
+//	@Summary		Create a new shelf at the end of the user's shelves, return the created shelf id
+//	@Description	Possible error code: BadInput, BadShelfName, ShelfAlreadyExists
+//	@Tags			shelf
+//	@Accept			json
+//	@Produce		json
+//	@Param			shelf			body		model.ShelfMetadata			true	"Shelf"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		201				{object}	createShelfResult
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		500
+//	@Router			/shelf/create [POST]
+func createShelf(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.ShelfMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkShelfMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if _, ok := db.GetShelfByName(session.UserID, input.Name); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(ShelfAlreadyExists))
+		}
+
+		uid, ok := db.CreateShelf(session.UserID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(
+			createShelfResult{
+				ShelfID: hex.EncodeToString(uid),
+			})
+	}
+}
+
+// Get User's Shelves
+//
+//	@Summary		Get the shelves of the user with provided username in the order the user set
+//	@Description	The private shelves are only included for the user themselves
+//	@Tags			shelf
+//	@Accept			json
+//	@Produce		json
+//	@Param			username		path		string						true	"Username"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	[]model.ShelfView
+//	@Failure		404
+//	@Failure		500
+//	@Router			/accounts/:username/shelves [GET]
+func getUserShelves(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := db.GetUser(c.Params("username"))
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		return c.JSON(db.GetUserShelves(user.ID, isSessionUser(c, user.ID)))
+	}
+}
+
+// Get Shelf's Novels
+//
+//	@Summary		Get a page of the novels on the shelf with provided shelf id
+//	@Description	The novels are in the order the owner set unless another order is requested. Only the owner can see a private shelf
+//	@Tags			shelf
+//	@Accept			json
+//	@Produce		json
+//	@Param			ShelfID			path		string						true	"Shelf ID"
+//	@Param			filtersAndSort	query		model.FiltersAndSortNovel	false	"Filters and sorting options, orderBy can also be position"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	[]model.NovelMetadataSmall
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/shelf/:shelfID [GET]
+func getShelfNovels(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shelfID, ok := getIDParam(c, "shelfID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		shelf, ok := db.GetShelf(shelfID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if shelf.Visibility == model.VisibilityPrivate && !isSessionUser(c, shelf.UserID) {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		filtersAndSort := getFiltersAndSort(c)
+		orderBy := model.OrderBy(c.Query(QueryOrderBy, ""))
+		if orderBy == "" || orderBy == model.OrderByShelfPosition {
+			filtersAndSort.OrderBy = model.OrderByShelfPosition
+			if c.Query(QuerySortOrder, "") == "" {
+				filtersAndSort.SortOrder = model.SortOrderAsc
+			}
+		}
+
+		return c.JSON(db.GetShelfNovels(shelf.ID, &filtersAndSort))
+	}
+}
+
+// Update Shelf
+//
+//	@Summary		Rename the shelf with provided shelf id or change its visibility
+//	@Description	Only the owner can update the shelf. Possible error code: BadInput, BadShelfName, ShelfAlreadyExists
+//	@Tags			shelf
+//	@Accept			json
+//	@Param			ShelfID			path	string						true	"Shelf ID"
+//	@Param			shelf			body	model.ShelfMetadata			true	"Shelf"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/shelf/:shelfID [PATCH]
+func updateShelf(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.ShelfMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkShelfMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		shelf, status := getOwnedShelf(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if other, ok := db.GetShelfByName(shelf.UserID, input.Name); ok && !bytes.Equal(other.ID, shelf.ID) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(ShelfAlreadyExists))
+		}
+
+		ok := db.UpdateShelf(shelf.ID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Delete Shelf
+//
+//	@Summary		Delete the shelf with provided shelf id
+//	@Description	Only the owner can delete the shelf, the novels on it are not affected
+//	@Tags			shelf
+//	@Accept			json
+//	@Param			ShelfID			path	string						true	"Shelf ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/shelf/:shelfID [DELETE]
+func deleteShelf(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		shelf, status := getOwnedShelf(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		ok := db.DeleteShelf(shelf.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Reorder Shelves
+//
+//	@Summary		Rewrite the order of the user's shelves
+//	@Description	The list must contain the id of every shelf of the user exactly once, possible error code: BadInput, BadOrder
+//	@Tags			shelf
+//	@Accept			json
+//	@Param			order			body	reorderInput				true	"Shelf ids in the new order"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		500
+//	@Router			/shelf/order [PATCH]
+func reorderShelves(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input reorderInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		shelfIDs, ok := decodeIDs(input.IDs)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadOrder))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		shelves := db.GetUserShelves(session.UserID, true)
+		existing := make([]string, 0, len(shelves))
+		for _, shelf := range shelves {
+			existing = append(existing, shelf.ID)
+		}
+		if !isSameIDList(input.IDs, existing) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadOrder))
+		}
+
+		ok = db.ReorderShelves(session.UserID, shelfIDs)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Add Novel to Shelf
+//
+//	@Summary		Put the novel at the end of the shelf, adding it twice has no effect
+//	@Description	Only the owner can add novels to the shelf
+//	@Tags			shelf
+//	@Accept			json
+//	@Param			ShelfID			path	string						true	"Shelf ID"
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/shelf/:shelfID/novel/:novelID [POST]
+func addNovelToShelf(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		shelf, status := getOwnedShelf(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if novel.Visibility == model.VisibilityPrivate && !bytes.Equal(novel.Author, shelf.UserID) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		ok = db.AddNovelToShelf(shelf.ID, novel.ID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Remove Novel from Shelf
+//
+//	@Summary		Take the novel off the shelf
+//	@Description	Only the owner can remove novels from the shelf
+//	@Tags			shelf
+//	@Accept			json
+//	@Param			ShelfID			path	string						true	"Shelf ID"
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/shelf/:shelfID/novel/:novelID [DELETE]
+func removeNovelFromShelf(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		shelf, status := getOwnedShelf(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		ok = db.RemoveNovelFromShelf(shelf.ID, novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Reorder Shelf's Novels
+//
+//	@Summary		Rewrite the order of the novels on the shelf
+//	@Description	The list must contain the id of every novel on the shelf exactly once, only the owner can reorder the shelf, possible error code: BadInput, BadOrder
+//	@Tags			shelf
+//	@Accept			json
+//	@Param			ShelfID			path	string						true	"Shelf ID"
+//	@Param			order			body	reorderInput				true	"Novel ids in the new order"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/shelf/:shelfID/order [PATCH]
+func reorderShelfNovels(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input reorderInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		novelIDs, ok := decodeIDs(input.IDs)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadOrder))
+		}
+
+		shelf, status := getOwnedShelf(c, db)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+		if !isSameIDList(input.IDs, db.GetShelfNovelIDs(shelf.ID)) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadOrder))
+		}
+
+		ok = db.ReorderShelfNovels(shelf.ID, novelIDs)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Return the shelf from the path if it belongs to the session user,
+// otherwise return the status code to response with
+func getOwnedShelf(c *fiber.Ctx, db model.DB) (model.Shelf, int) {
+	session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+	if !ok {
+		log.Warn("Check the authentication middleware")
+		return model.Shelf{}, fiber.StatusInternalServerError
+	}
+
+	shelfID, ok := getIDParam(c, "shelfID")
+	if !ok {
+		return model.Shelf{}, fiber.StatusNotFound
+	}
+	shelf, ok := db.GetShelf(shelfID)
+	if !ok {
+		return model.Shelf{}, fiber.StatusNotFound
+	}
+	if !bytes.Equal(shelf.UserID, session.UserID) {
+		return model.Shelf{}, fiber.StatusUnauthorized
+	}
+
+	return shelf, fiber.StatusOK
+}
+
+func checkShelfMetadata(input *model.ShelfMetadata) (bool, ErrorCode) {
+	input.Name = strings.TrimSpace(input.Name)
+	nameLength := utf8.RuneCountInString(input.Name)
+	if nameLength < model.ShelfNameMinLength || nameLength > model.ShelfNameMaxLength {
+		return false, BadShelfName
+	}
+
+	if input.Visibility.String() == model.Unknown {
+		return false, BadInput
+	}
+
+	return true, BadInput
+}
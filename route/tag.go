@@ -0,0 +1,298 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"strings"
+	"unicode/utf8"
+)
+
+func AddTagRoutes(router *fiber.Router, db model.DB) {
+	tagRoute := (*router).Group("/tag")
+
+	tagRoute.Get("/", findTags(db))
+	tagRoute.Get("/:tagID", getTag(db))
+
+	manageTags := middleware.RequirePermission(model.PermissionManageTags)
+	tagRoute.Post("/create", manageTags, createTag(db))
+	tagRoute.Post("/:tagID/merge", manageTags, mergeTag(db))
+
+	tagRoute.Patch("/:tagID", manageTags, updateTag(db))
+}
+
+func addNovelTagRoutes(novelRoute fiber.Router, db model.DB) {
+	novelRoute.Patch("/:novelID/tags", setNovelTags(db))
+}
+
+// Find Tags
+//
+//	@Summary	Get the tags whose name starts with the search string in alphabetical order
+//	@Tags		tag
+//	@Produce	json
+//	@Param		search	query		string	false	"Start of the tag name, every tag is returned if empty"
+//	@Param		page	query		uint	false	"Page"
+//	@Success	200		{object}	[]model.Tag
+//	@Failure	500
+//	@Router		/tag [GET]
+func findTags(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page := c.QueryInt(QueryPage, 1)
+		pageUint := uint(page)
+		if page < 1 {
+			pageUint = 1
+		}
+
+		return c.JSON(db.FindTags(c.Query(QuerySearch, ""), pageUint))
+	}
+}
+
+// Get Tag
+//
+//	@Summary	Get the tag with provided tag id
+//	@Tags		tag
+//	@Produce	json
+//	@Param		TagID	path		int	true	"Tag ID"
+//	@Success	200		{object}	model.Tag
+//	@Failure	404
+//	@Failure	500
+//	@Router		/tag/:tagID [GET]
+func getTag(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tagID, err := c.ParamsInt("tagID")
+		if err != nil {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		tag, ok := db.GetTag(tagID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		return c.JSON(tag)
+	}
+}
+
+type createTagResult struct {
+	TagID int `json:"tag_id"`
+}
+
+// Create Tag
+//
+//	@Summary		Create a new tag, return the created tag id
+//	@Description	Only users with the manage_tags permission can create tags. Possible error code: BadInput, BadTagName, TagDescriptionTooLong, TagAlreadyExists
+//	@Tags			tag
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag				body		model.TagMetadata			true	"Tag"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		201				{object}	createTagResult
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		500
+//	@Router			/tag/create [POST]
+func createTag(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input model.TagMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkTagMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+		if _, ok := db.GetTagByName(input.Name); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(TagAlreadyExists))
+		}
+
+		tagID, ok := db.CreateTag(&input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(
+			createTagResult{
+				TagID: tagID,
+			})
+	}
+}
+
+// Update Tag
+//
+//	@Summary		Update the tag with provided tag id
+//	@Description	Only users with the manage_tags permission can update tags. Possible error code: BadInput, BadTagName, TagDescriptionTooLong, TagAlreadyExists
+//	@Tags			tag
+//	@Accept			json
+//	@Param			TagID			path	int							true	"Tag ID"
+//	@Param			tag				body	model.TagMetadata			true	"Tag"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/tag/:tagID [PATCH]
+func updateTag(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input model.TagMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkTagMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		tagID, err := c.ParamsInt("tagID")
+		if err != nil {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if _, ok := db.GetTag(tagID); !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if tag, ok := db.GetTagByName(input.Name); ok && tag.ID != tagID {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(TagAlreadyExists))
+		}
+
+		ok := db.UpdateTag(tagID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+type mergeTagInput struct {
+	Into int `json:"into"`
+}
+
+// Merge Tag
+//
+//	@Summary		Merge the tag with provided tag id into another tag
+//	@Description	Every novel of the tag is moved to the other tag, then the tag is deleted. Only users with the manage_tags permission can merge tags
+//	@Tags			tag
+//	@Accept			json
+//	@Param			TagID			path	int							true	"Tag ID"
+//	@Param			into			body	mergeTagInput				true	"The tag to merge into"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/tag/:tagID/merge [POST]
+func mergeTag(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input mergeTagInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		tagID, err := c.ParamsInt("tagID")
+		if err != nil {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if tagID == input.Into {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if !db.TagsExist([]int{tagID, input.Into}) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		ok := db.MergeTags(tagID, input.Into)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+type novelTagsInput struct {
+	Tags []int `json:"tags"`
+}
+
+// Set Novel's Tags
+//
+//	@Summary		Replace the tags of the novel with provided novel id
+//	@Description	Only the author of the novel can set its tags. Possible error code: BadInput, BadTag
+//	@Tags			tag
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			tags			body	novelTagsInput				true	"Tag IDs"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/tags [PATCH]
+func setNovelTags(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input novelTagsInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if ok, code := checkTagIDs(db, input.Tags); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ok = db.SetNovelTags(novelID, input.Tags)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+func checkTagMetadata(input *model.TagMetadata) (bool, ErrorCode) {
+	input.Name = strings.TrimSpace(input.Name)
+	nameLength := utf8.RuneCountInString(input.Name)
+	if nameLength < model.TagNameMinLength || nameLength > model.TagNameMaxLength {
+		return false, BadTagName
+	}
+
+	if utf8.RuneCountInString(input.Description) > model.TagDescriptionMaxLength {
+		return false, TagDescriptionTooLong
+	}
+
+	return true, BadInput
+}
+
+// Check if the novel can have the tags in the list
+func checkTagIDs(db model.DB, tagIDs []int) (bool, ErrorCode) {
+	if len(tagIDs) > model.NovelMaxTags || !db.TagsExist(tagIDs) {
+		return false, BadTag
+	}
+
+	return true, BadInput
+}
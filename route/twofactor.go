@@ -0,0 +1,415 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"Lightnovel/token"
+	"Lightnovel/totp"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The name the authenticator apps show next to the codes
+const twoFactorIssuer = "Light novel"
+
+func addUserTwoFactorRoutes(accountRoute fiber.Router, db model.DB) {
+	addReadRoute(accountRoute, "/2fa", getTwoFactorStatus(db))
+
+	accountRoute.Post("/2fa/setup", setupTwoFactor(db))
+	accountRoute.Post("/2fa/confirm", confirmTwoFactor(db))
+	accountRoute.Post("/2fa/disable", disableTwoFactor(db))
+	accountRoute.Post("/2fa/recovery", regenerateRecoveryCodes(db))
+}
+
+type twoFactorInput struct {
+	Password string `json:"password"`
+	// A code of the authenticator app or a recovery code
+	Code string `json:"code"`
+}
+
+type twoFactorLoginInput struct {
+	Challenge  string `json:"challenge"`
+	Code       string `json:"code"`
+	DeviceName string `json:"deviceName"`
+}
+
+func (input *twoFactorLoginInput) Validate() (bool, ErrorCode) {
+	input.DeviceName = strings.TrimFunc(input.DeviceName, func(r rune) bool {
+		return !unicode.IsPrint(r)
+	})
+	if utf8.RuneCountInString(input.DeviceName) > model.DeviceNameMaxLength {
+		return false, BadDeviceName
+	}
+	if !isUserTokenValid(input.Challenge) {
+		return false, BadToken
+	}
+	if input.Code == "" {
+		return false, TwoFactorRequired
+	}
+	return true, BadInput
+}
+
+// Check the code when the user enabled the two-factor authentication, a code of
+// the app is accepted once and a recovery code is consumed. The codes are refused
+// for a while after too many wrong ones so they cannot be guessed
+func checkTwoFactorCode(db model.DB, user *model.User, code string) (bool, ErrorCode) {
+	if user.TOTPSecret == nil {
+		return true, BadInput
+	}
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false, TwoFactorRequired
+	}
+	if !db.StartTOTPAttempt(user.ID) {
+		return false, TwoFactorLocked
+	}
+
+	accepted := false
+	if counter, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		accepted = db.UseTOTPCounter(user.ID, counter)
+	} else if recoveryCode := totp.NormalizeRecoveryCode(code); recoveryCode != "" {
+		accepted = db.ConsumeRecoveryCode(user.ID, recoveryCode)
+	}
+	if !accepted {
+		return false, WrongTwoFactorCode
+	}
+	db.ResetTOTPAttempts(user.ID)
+	return true, BadInput
+}
+
+// Login With Two-Factor Code
+//
+//	@Summary		Finish the login of a user with two-factor authentication, return a new user session
+//	@Description	The challenge is returned by the login and can only be tried once, a wrong code requires to log in again. Possible error: BadInput, BadDeviceName, BadToken, TwoFactorRequired, WrongTwoFactorCode, TwoFactorLocked, UserBanned
+//	@Tags			accounts
+//	@Accept			json
+//	@Produce		json
+//	@Param			input	body		twoFactorLoginInput	true	"Challenge and code"
+//	@Success		200		{object}	model.SessionInfo
+//	@Failure		400		{object}	ErrorJSON
+//	@Failure		500
+//	@Router			/accounts/login/2fa [POST]
+func loginTwoFactor(db model.DB, issuer *token.Issuer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input twoFactorLoginInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		if ok, code := input.Validate(); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		challenge, ok := db.ConsumeUserToken(input.Challenge, model.TokenLoginChallenge)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadToken))
+		}
+		user, ok := db.GetUserByID(challenge.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if user.BannedAt.Valid {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(UserBanned))
+		}
+		if ok, code := checkTwoFactorCode(db, &user, input.Code); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		sessionInfo, ok := db.CreateSession(user.ID, input.DeviceName, c.IP())
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if !issueAccessToken(issuer, &sessionInfo, user.ID, user.Role) {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		setSessionCookie(c, sessionInfo)
+		return c.JSON(sessionInfo)
+	}
+}
+
+// Get Two-Factor Status
+//
+//	@Summary	Get whether the two-factor authentication is enabled and the number of recovery codes left
+//	@Tags		accounts
+//	@Accept		json
+//	@Produce	json
+//	@Param		sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success	200				{object}	model.TwoFactorStatus
+//	@Failure	401
+//	@Failure	500
+//	@Router		/accounts/2fa [GET]
+func getTwoFactorStatus(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUserByID(session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		status := model.TwoFactorStatus{Enabled: user.TOTPSecret != nil}
+		if status.Enabled {
+			status.RecoveryCodesLeft = db.CountRecoveryCodes(user.ID)
+		}
+		return c.JSON(status)
+	}
+}
+
+// Setup Two-Factor
+//
+//	@Summary		Start enabling the two-factor authentication, return the secret to add to an authenticator app
+//	@Description	The two-factor authentication is enabled once a code of the app is sent to confirm. Possible error: BadInput, BadPassword, WrongPassword, TwoFactorAlreadyEnabled
+//	@Tags			accounts
+//	@Accept			json
+//	@Produce		json
+//	@Param			input			body		twoFactorInput				true	"Password"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		200				{object}	model.TwoFactorSetup
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		500
+//	@Router			/accounts/2fa/setup [POST]
+func setupTwoFactor(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input twoFactorInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if !IsPasswordValid(input.Password) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadPassword))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUserByID(session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if !PasswordVerify(input.Password, user.Password) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(WrongPassword))
+		}
+		if user.TOTPSecret != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(TwoFactorAlreadyEnabled))
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			log.Error(err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if !db.SetPendingTOTPSecret(user.ID, secret) {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.JSON(model.TwoFactorSetup{
+			Secret: totp.EncodeSecret(secret),
+			URI:    totp.ProvisioningURI(twoFactorIssuer, user.Username, secret),
+		})
+	}
+}
+
+// Confirm Two-Factor
+//
+//	@Summary		Enable the two-factor authentication with a code of the authenticator app, return the recovery codes
+//	@Description	The recovery codes are only shown once, each of them can replace a code of the app one time. Possible error: BadInput, TwoFactorRequired, WrongTwoFactorCode, TwoFactorAlreadyEnabled, TwoFactorNotEnabled
+//	@Tags			accounts
+//	@Accept			json
+//	@Produce		json
+//	@Param			input			body		twoFactorInput				true	"Code"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		200				{object}	model.RecoveryCodes
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		500
+//	@Router			/accounts/2fa/confirm [POST]
+func confirmTwoFactor(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input twoFactorInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if input.Code == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(TwoFactorRequired))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUserByID(session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if user.TOTPSecret != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(TwoFactorAlreadyEnabled))
+		}
+		// The setup was not started
+		if user.TOTPPendingSecret == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(TwoFactorNotEnabled))
+		}
+
+		counter, ok := totp.Validate(user.TOTPPendingSecret, input.Code, time.Now())
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(WrongTwoFactorCode))
+		}
+
+		recoveryCodes, err := totp.GenerateRecoveryCodes()
+		if err != nil {
+			log.Error(err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		// Fails if the setup was started again since the user was read
+		if !db.EnableTOTP(user.ID, user.TOTPPendingSecret, counter, recoveryCodes) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(WrongTwoFactorCode))
+		}
+
+		return c.JSON(model.RecoveryCodes{Codes: recoveryCodes})
+	}
+}
+
+// Disable Two-Factor
+//
+//	@Summary		Disable the two-factor authentication and delete the recovery codes
+//	@Description	Possible error: BadInput, BadPassword, WrongPassword, TwoFactorRequired, WrongTwoFactorCode, TwoFactorLocked, TwoFactorNotEnabled
+//	@Tags			accounts
+//	@Accept			json
+//	@Param			input			body	twoFactorInput				true	"Password and code"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		500
+//	@Router			/accounts/2fa/disable [POST]
+func disableTwoFactor(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input twoFactorInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if !IsPasswordValid(input.Password) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadPassword))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUserByID(session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if user.TOTPSecret == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(TwoFactorNotEnabled))
+		}
+		if !PasswordVerify(input.Password, user.Password) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(WrongPassword))
+		}
+		if ok, code := checkTwoFactorCode(db, &user, input.Code); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		if !db.DisableTOTP(user.ID) {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Regenerate Recovery Codes
+//
+//	@Summary		Replace the recovery codes with new ones, return the new codes
+//	@Description	Possible error: BadInput, BadPassword, WrongPassword, TwoFactorRequired, WrongTwoFactorCode, TwoFactorLocked, TwoFactorNotEnabled
+//	@Tags			accounts
+//	@Accept			json
+//	@Produce		json
+//	@Param			input			body		twoFactorInput				true	"Password and code"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		200				{object}	model.RecoveryCodes
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		500
+//	@Router			/accounts/2fa/recovery [POST]
+func regenerateRecoveryCodes(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input twoFactorInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		if !IsPasswordValid(input.Password) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadPassword))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		user, ok := db.GetUserByID(session.UserID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if user.TOTPSecret == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(TwoFactorNotEnabled))
+		}
+		if !PasswordVerify(input.Password, user.Password) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(WrongPassword))
+		}
+		if ok, code := checkTwoFactorCode(db, &user, input.Code); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		recoveryCodes, err := totp.GenerateRecoveryCodes()
+		if err != nil {
+			log.Error(err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if !db.ReplaceRecoveryCodes(user.ID, recoveryCodes) {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.JSON(model.RecoveryCodes{Codes: recoveryCodes})
+	}
+}
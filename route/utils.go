@@ -1,10 +1,16 @@
 package route
 
 import (
+	"Lightnovel/middleware"
 	"Lightnovel/model"
+	"Lightnovel/token"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 	"golang.org/x/crypto/bcrypt"
 	"net/mail"
 	"regexp"
@@ -23,6 +29,169 @@ func Unhex(s string) ([]byte, error) {
 	return hex.DecodeString(s[:model.IDHexLength])
 }
 
+// Return the decoded ID from the path parameter with the provided key
+func getIDParam(c *fiber.Ctx, key string) ([]byte, bool) {
+	idStr := c.Params(key)
+	if len(idStr) != model.IDHexLength {
+		return nil, false
+	}
+	id, err := Unhex(idStr)
+	if err != nil {
+		return nil, false
+	}
+	return id, true
+}
+
+// Return the ID of the logged in user, nil for a guest
+func getViewerID(c *fiber.Ctx) []byte {
+	if c.Locals(middleware.KeyIsUserAuth) != true {
+		return nil
+	}
+	session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+	if !ok {
+		return nil
+	}
+	return session.UserID
+}
+
+// Check if the request is authenticated as the user with the provided ID
+func isSessionUser(c *fiber.Ctx, userID []byte) bool {
+	if c.Locals(middleware.KeyIsUserAuth) != true {
+		return false
+	}
+	session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+	return ok && bytes.Equal(session.UserID, userID)
+}
+
+// The path the refresh cookie is sent to, the refresh token is only needed to
+// renew the session or to log out
+const refreshCookiePath = "/api/v1/accounts"
+
+// Sign the access token of the session for the user and add it to the session info
+func issueAccessToken(
+	issuer *token.Issuer,
+	sessionInfo *model.SessionInfo,
+	userID []byte,
+	role model.Role,
+) bool {
+	accessToken, expires, err := issuer.Issue(token.Claims{
+		Subject:   hex.EncodeToString(userID),
+		SessionID: sessionInfo.Session,
+		Role:      string(role),
+	})
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	sessionInfo.AccessToken = accessToken
+	sessionInfo.AccessExpiredAt = expires
+	return true
+}
+
+// Store the access token and the refresh token in HttpOnly cookies so browsers
+// do not need to keep them where scripts can read them
+func setSessionCookie(c *fiber.Ctx, sessionInfo model.SessionInfo) {
+	c.Cookie(&fiber.Cookie{
+		Name:     middleware.CookieSession,
+		Value:    sessionInfo.AccessToken,
+		Path:     "/",
+		Expires:  sessionInfo.AccessExpiredAt,
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     middleware.CookieRefresh,
+		Value:    sessionInfo.Session,
+		Path:     refreshCookiePath,
+		Expires:  sessionInfo.ExpiredAt,
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+}
+
+func clearSessionCookies(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:    middleware.CookieSession,
+		Path:    "/",
+		Expires: time.Unix(0, 0),
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:    middleware.CookieRefresh,
+		Path:    refreshCookiePath,
+		Expires: time.Unix(0, 0),
+	})
+}
+
+// Return the refresh token from the refresh cookie, or from the body for the
+// clients not using cookies
+func getRefreshToken(c *fiber.Ctx) ([]byte, bool) {
+	refreshToken := c.Cookies(middleware.CookieRefresh)
+	if refreshToken == "" {
+		var body model.IncludeSessionString
+		if err := c.BodyParser(&body); err != nil {
+			return nil, false
+		}
+		refreshToken = body.Session
+	}
+
+	if len(refreshToken) != model.IDHexLength {
+		return nil, false
+	}
+	sessionID, err := hex.DecodeString(refreshToken)
+	if err != nil {
+		return nil, false
+	}
+	return sessionID, true
+}
+
+// Register the read-only handler on GET, it is also kept on POST for the clients
+// that still send the session in the body
+func addReadRoute(router fiber.Router, path string, handlers ...fiber.Handler) {
+	router.Get(path, handlers...)
+	router.Post(path, handlers...)
+}
+
+type reorderInput struct {
+	IDs []string `json:"ids"`
+}
+
+// Decode the hex IDs, return false if any of them is not a valid ID
+func decodeIDs(idStrs []string) ([][]byte, bool) {
+	ids := make([][]byte, 0, len(idStrs))
+	for _, idStr := range idStrs {
+		if len(idStr) != model.IDHexLength {
+			return nil, false
+		}
+		id, err := Unhex(idStr)
+		if err != nil {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// Check if ids contains every ID in existing exactly once and nothing else
+func isSameIDList(ids []string, existing []string) bool {
+	if len(ids) != len(existing) {
+		return false
+	}
+	remain := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		remain[strings.ToLower(id)] = true
+	}
+	for _, id := range ids {
+		id = strings.ToLower(id)
+		if !remain[id] {
+			return false
+		}
+		delete(remain, id)
+	}
+	return true
+}
+
 func PasswordVerify(password string, hash []byte) bool {
 	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
 	return err == nil
@@ -88,6 +257,8 @@ const (
 	QueryFromDate   = "from"
 	QueryToDate     = "to"
 	QueryStatus     = "status"
+	QueryReviewSort = "sort"
+	QueryCursor     = "cursor"
 )
 
 func getFiltersAndSort(c *fiber.Ctx) model.FiltersAndSortNovel {
@@ -129,3 +300,194 @@ func getFiltersAndSort(c *fiber.Ctx) model.FiltersAndSortNovel {
 		),
 	}
 }
+
+// Encode the cursor as an opaque string of the creation time in unix seconds
+// followed by the ID, an empty string is returned for a nil cursor
+func encodeCursor(cursor *model.CommentCursor) string {
+	if cursor == nil {
+		return ""
+	}
+	buf := make([]byte, 8, 8+len(cursor.ID))
+	binary.BigEndian.PutUint64(buf, uint64(cursor.CreateAt.Unix()))
+	return base64.RawURLEncoding.EncodeToString(append(buf, cursor.ID...))
+}
+
+// Decode the cursor made by encodeCursor, nil is returned for an empty or bad string
+func decodeCursor(s string) *model.CommentCursor {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(buf) != 8+model.IDBinLength {
+		return nil
+	}
+	return &model.CommentCursor{
+		CreateAt: time.Unix(int64(binary.BigEndian.Uint64(buf[:8])), 0),
+		ID:       buf[8:],
+	}
+}
+
+// Split the chapter content into paragraphs, paragraphs are separated by blank lines
+func splitParagraphs(content string) []string {
+	var paragraphs []string
+	var lines []string
+	flush := func() {
+		if len(lines) != 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+			lines = nil
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return paragraphs
+}
+
+// The largest table the paragraph matching is allowed to build, the changed part
+// of larger edits is treated as entirely rewritten
+const maxParagraphMatchCells = 1 << 22
+
+// Return the new index of every old paragraph. Paragraphs kept by the edit are
+// matched with a longest common subsequence and moved paragraphs by their text,
+// a changed paragraph keeps its offset inside the rewritten part when possible,
+// otherwise it moves to the nearest kept paragraph
+func mapParagraphs(oldParagraphs []string, newParagraphs []string) []int {
+	paragraphMap := make([]int, len(oldParagraphs))
+	if len(newParagraphs) == 0 {
+		return paragraphMap
+	}
+
+	// matches[i] is the new index of the old paragraph i, or -1 if it was changed
+	matches := make([]int, len(oldParagraphs))
+	for i := range matches {
+		matches[i] = -1
+	}
+	prefix := 0
+	for prefix < len(oldParagraphs) && prefix < len(newParagraphs) &&
+		oldParagraphs[prefix] == newParagraphs[prefix] {
+		matches[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldParagraphs)-prefix && suffix < len(newParagraphs)-prefix &&
+		oldParagraphs[len(oldParagraphs)-1-suffix] == newParagraphs[len(newParagraphs)-1-suffix] {
+		matches[len(oldParagraphs)-1-suffix] = len(newParagraphs) - 1 - suffix
+		suffix++
+	}
+	oldMiddle := oldParagraphs[prefix : len(oldParagraphs)-suffix]
+	newMiddle := newParagraphs[prefix : len(newParagraphs)-suffix]
+	if len(oldMiddle)*len(newMiddle) <= maxParagraphMatchCells {
+		for i, j := range matchParagraphs(oldMiddle, newMiddle) {
+			if j >= 0 {
+				matches[prefix+i] = prefix + j
+			}
+		}
+	}
+
+	// Paragraphs moved by the edit are found by their text when it is unique
+	matched := make([]bool, len(newParagraphs))
+	for _, j := range matches {
+		if j >= 0 {
+			matched[j] = true
+		}
+	}
+	moved := make(map[string]int)
+	for j, paragraph := range newParagraphs {
+		if matched[j] {
+			continue
+		}
+		if _, ok := moved[paragraph]; ok {
+			moved[paragraph] = -1
+		} else {
+			moved[paragraph] = j
+		}
+	}
+
+	for i := range oldParagraphs {
+		if matches[i] >= 0 {
+			paragraphMap[i] = matches[i]
+			continue
+		}
+		if j, ok := moved[oldParagraphs[i]]; ok && j >= 0 {
+			paragraphMap[i] = j
+			continue
+		}
+
+		prev := i - 1
+		for prev >= 0 && matches[prev] < 0 {
+			prev--
+		}
+		next := i + 1
+		for next < len(oldParagraphs) && matches[next] < 0 {
+			next++
+		}
+
+		// The rewritten part of the new content between the kept paragraphs
+		low, high := 0, len(newParagraphs)-1
+		if prev >= 0 {
+			low = matches[prev] + 1
+		}
+		if next < len(oldParagraphs) {
+			high = matches[next] - 1
+		}
+		if low <= high {
+			paragraphMap[i] = low + (i - prev - 1)
+			if paragraphMap[i] > high {
+				paragraphMap[i] = high
+			}
+			continue
+		}
+
+		// The part was removed, move to the nearest kept paragraph
+		if prev >= 0 && (next >= len(oldParagraphs) || i-prev <= next-i) {
+			paragraphMap[i] = matches[prev]
+		} else {
+			paragraphMap[i] = matches[next]
+		}
+	}
+	return paragraphMap
+}
+
+// Return the index in b of every paragraph of a in their longest common
+// subsequence, -1 for the paragraphs of a not in the subsequence
+func matchParagraphs(a []string, b []string) []int {
+	matches := make([]int, len(a))
+	for i := range matches {
+		matches[i] = -1
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return matches
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	width := len(b) + 1
+	lengths := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i*width+j] = lengths[(i+1)*width+j+1] + 1
+			} else if lengths[(i+1)*width+j] >= lengths[i*width+j+1] {
+				lengths[i*width+j] = lengths[(i+1)*width+j]
+			} else {
+				lengths[i*width+j] = lengths[i*width+j+1]
+			}
+		}
+	}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			matches[i] = j
+			i++
+			j++
+		case lengths[(i+1)*width+j] >= lengths[i*width+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
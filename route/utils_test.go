@@ -3,7 +3,9 @@ package route
 import (
 	"Lightnovel/model"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIsPasswordValid(t *testing.T) {
@@ -134,3 +136,223 @@ func Test_parseIntArray(t *testing.T) {
 		})
 	}
 }
+
+func Test_isSameIDList(t *testing.T) {
+	const (
+		idA = "0123456789abcdef0123456789abcdef"
+		idB = "fedcba9876543210fedcba9876543210"
+		idC = "00000000000000000000000000000000"
+	)
+	tests := []struct {
+		name     string
+		ids      []string
+		existing []string
+		want     bool
+	}{
+		{"Same order", []string{idA, idB}, []string{idA, idB}, true},
+		{"Different order", []string{idB, idA}, []string{idA, idB}, true},
+		{"Upper case id", []string{"0123456789ABCDEF0123456789ABCDEF", idB}, []string{idA, idB}, true},
+		{"Missing id", []string{idA}, []string{idA, idB}, false},
+		{"Foreign id", []string{idA, idC}, []string{idA, idB}, false},
+		{"Duplicated id", []string{idA, idA}, []string{idA, idB}, false},
+		{"Both empty", []string{}, []string{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSameIDList(tt.ids, tt.existing); got != tt.want {
+				t.Errorf("isSameIDList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cursor(t *testing.T) {
+	cursor := &model.CommentCursor{
+		CreateAt: time.Unix(1700000000, 0),
+		ID:       []byte("0123456789abcdef"),
+	}
+	got := decodeCursor(encodeCursor(cursor))
+	if got == nil || !got.CreateAt.Equal(cursor.CreateAt) || !reflect.DeepEqual(got.ID, cursor.ID) {
+		t.Errorf("decodeCursor(encodeCursor()) = %v, want %v", got, cursor)
+	}
+
+	if got := encodeCursor(nil); got != "" {
+		t.Errorf("encodeCursor(nil) = %v, want empty string", got)
+	}
+	for _, s := range []string{"", "not a cursor", "AAAA"} {
+		if got := decodeCursor(s); got != nil {
+			t.Errorf("decodeCursor(%q) = %v, want nil", s, got)
+		}
+	}
+}
+
+func Test_checkTagMetadata(t *testing.T) {
+	tests := []struct {
+		name  string
+		input model.TagMetadata
+		want  bool
+		code  ErrorCode
+	}{
+		{"Ok tag", model.TagMetadata{Name: "Isekai", Description: "Another world"}, true, BadInput},
+		{"Short name", model.TagMetadata{Name: "A"}, false, BadTagName},
+		{"Blank name", model.TagMetadata{Name: "   "}, false, BadTagName},
+		{"Long name", model.TagMetadata{Name: strings.Repeat("a", model.TagNameMaxLength+1)}, false, BadTagName},
+		{
+			"Long description",
+			model.TagMetadata{Name: "Isekai", Description: strings.Repeat("a", model.TagDescriptionMaxLength+1)},
+			false,
+			TagDescriptionTooLong,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, code := checkTagMetadata(&tt.input)
+			if got != tt.want || code != tt.code {
+				t.Errorf("checkTagMetadata() = %v, %v, want %v, %v", got, code, tt.want, tt.code)
+			}
+		})
+	}
+}
+
+func Test_checkResolveReportsInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input resolveReportsInput
+		want  bool
+		code  ErrorCode
+	}{
+		{"Resolve", resolveReportsInput{Action: model.ReportActionResolve, Outcome: "Warned"}, true, BadInput},
+		{"Dismiss without outcome", resolveReportsInput{Action: model.ReportActionDismiss}, true, BadInput},
+		{"Take action", resolveReportsInput{Action: model.ReportActionTakeAction}, true, BadInput},
+		{"Unknown action", resolveReportsInput{Action: "ban"}, false, BadReportAction},
+		{"Missing action", resolveReportsInput{Outcome: "Warned"}, false, BadReportAction},
+		{
+			"Long outcome",
+			resolveReportsInput{
+				Action:  model.ReportActionResolve,
+				Outcome: strings.Repeat("a", model.ReportOutcomeMaxLength+1),
+			},
+			false,
+			ReportOutcomeTooLong,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, code := checkResolveReportsInput(&tt.input)
+			if got != tt.want || code != tt.code {
+				t.Errorf("checkResolveReportsInput() = %v, %v, want %v, %v", got, code, tt.want, tt.code)
+			}
+		})
+	}
+}
+
+func Test_splitParagraphs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"Empty", "", nil},
+		{"Blank", " \n\n \t\n", nil},
+		{"Single", "First line\nsecond line", []string{"First line\nsecond line"}},
+		{"Blank lines", "One\n\n\nTwo\r\n  \r\nThree", []string{"One", "Two", "Three"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitParagraphs(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitParagraphs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mapParagraphs(t *testing.T) {
+	tests := []struct {
+		name          string
+		oldParagraphs []string
+		newParagraphs []string
+		want          []int
+	}{
+		{"Unchanged", []string{"a", "b", "c"}, []string{"a", "b", "c"}, []int{0, 1, 2}},
+		{"Inserted before", []string{"a", "b"}, []string{"x", "y", "a", "b"}, []int{2, 3}},
+		{"Edited in place", []string{"a", "b", "c"}, []string{"a", "B", "c"}, []int{0, 1, 2}},
+		{"Removed", []string{"a", "b", "c", "d"}, []string{"a", "d"}, []int{0, 0, 1, 1}},
+		{"Removed at the end", []string{"a", "b", "c"}, []string{"a"}, []int{0, 0, 0}},
+		{"Moved", []string{"a", "b", "c"}, []string{"c", "a", "b"}, []int{1, 2, 0}},
+		{"Rewritten", []string{"a", "b", "c"}, []string{"x", "y"}, []int{0, 1, 1}},
+		{"Emptied", []string{"a", "b"}, nil, []int{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mapParagraphs(tt.oldParagraphs, tt.newParagraphs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mapParagraphs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkShelfMetadata(t *testing.T) {
+	tests := []struct {
+		name  string
+		input model.ShelfMetadata
+		want  ErrorCode
+		ok    bool
+	}{
+		{"Valid", model.ShelfMetadata{Name: " Favorites ", Visibility: model.VisibilityPublic}, BadInput, true},
+		{"Empty name", model.ShelfMetadata{Name: "  ", Visibility: model.VisibilityPublic}, BadShelfName, false},
+		{"Long name", model.ShelfMetadata{Name: strings.Repeat("a", model.ShelfNameMaxLength+1), Visibility: model.VisibilityPrivate}, BadShelfName, false},
+		{"Bad visibility", model.ShelfMetadata{Name: "Reading", Visibility: 0}, BadInput, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, code := checkShelfMetadata(&tt.input)
+			if ok != tt.ok || code != tt.want {
+				t.Errorf("checkShelfMetadata() = %v, %v, want %v, %v", ok, code, tt.ok, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isUserTokenValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"Valid", strings.Repeat("0a", 32), true},
+		{"Short", strings.Repeat("0a", 31), false},
+		{"Not hex", strings.Repeat("zz", 32), false},
+		{"Empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUserTokenValid(tt.token); got != tt.want {
+				t.Errorf("isUserTokenValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_twoFactorLoginInput_Validate(t *testing.T) {
+	challenge := strings.Repeat("0a", 32)
+	tests := []struct {
+		name  string
+		input twoFactorLoginInput
+		want  ErrorCode
+		ok    bool
+	}{
+		{"Valid", twoFactorLoginInput{Challenge: challenge, Code: "123456", DeviceName: "Phone"}, BadInput, true},
+		{"Bad challenge", twoFactorLoginInput{Challenge: "abc", Code: "123456"}, BadToken, false},
+		{"No code", twoFactorLoginInput{Challenge: challenge}, TwoFactorRequired, false},
+		{"Long device name", twoFactorLoginInput{Challenge: challenge, Code: "123456", DeviceName: strings.Repeat("a", model.DeviceNameMaxLength+1)}, BadDeviceName, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, code := tt.input.Validate()
+			if ok != tt.ok || code != tt.want {
+				t.Errorf("Validate() = %v, %v, want %v, %v", ok, code, tt.ok, tt.want)
+			}
+		})
+	}
+}
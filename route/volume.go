@@ -0,0 +1,387 @@
+package route
+
+import (
+	"Lightnovel/middleware"
+	"Lightnovel/model"
+	"bytes"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"unicode/utf8"
+)
+
+func addVolumeRoutes(novelRoute fiber.Router, db model.DB) {
+	addReadRoute(novelRoute, "/:novelID/volume", getNovelVolumes(db))
+	novelRoute.Post("/:novelID/volume/create", createVolume(db))
+	addReadRoute(novelRoute, "/:novelID/volume/:volumeID", getVolume(db))
+
+	novelRoute.Patch("/:novelID/volume/order", reorderVolumes(db))
+	novelRoute.Patch("/:novelID/volume/:volumeID", updateVolumeMetadata(db))
+
+	novelRoute.Delete("/:novelID/volume/:volumeID", deleteVolume(db))
+}
+
+// Get Novel's Volumes
+//
+//	@Summary		Get all the volumes of the novel with provided novel id
+//	@Description	Private volumes are only returned to the author, if the novel is private, the user need to be logged in with the author account
+//	@Tags			volume
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	[]model.VolumeView
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/volume [GET]
+func getNovelVolumes(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		isAuthor := isSessionUser(c, novel.Author)
+		if novel.Visibility == model.VisibilityPrivate && !isAuthor {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		return c.JSON(db.GetNovelVolumes(novelID, isAuthor))
+	}
+}
+
+// Get Volume
+//
+//	@Summary		Get the volume with provided volume id
+//	@Description	If the volume or its novel is private, the user need to be logged in with the author account
+//	@Tags			volume
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			VolumeID		path		string						true	"Volume ID"
+//	@Param			sessionString	body		model.IncludeSessionString	false	"User's Session"
+//	@Success		200				{object}	model.VolumeView
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/volume/:volumeID [GET]
+func getVolume(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		volumeID, ok := getIDParam(c, "volumeID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		volumeView, ok := db.GetVolumeView(volumeID)
+		if !ok || volumeView.NovelID != hex.EncodeToString(novelID) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		if novel.Visibility == model.VisibilityPrivate ||
+			volumeView.Visibility == model.VisibilityPrivate.String() {
+			if !isSessionUser(c, novel.Author) {
+				return c.SendStatus(fiber.StatusUnauthorized)
+			}
+		}
+
+		return c.JSON(volumeView)
+	}
+}
+
+type createVolumeResult struct {
+	VolumeID string `json:"volume_id"`
+}
+
+// Create Volume
+//
+//	@Summary		Create a new volume in the novel with the provided metadata, return the created volume id
+//	@Description	Only the author of the novel can create volumes, possible error code: BadInput, TitleTooLong, TaglineTooLong, DescriptionTooLong
+//	@Tags			volume
+//	@Accept			json
+//	@Produce		json
+//	@Param			NovelID			path		string						true	"Novel ID"
+//	@Param			VolumeDetails	body		model.VolumeMetadata		true	"Volume details"
+//	@Param			sessionString	body		model.IncludeSessionString	true	"User's Session"
+//	@Success		201				{object}	createVolumeResult
+//	@Failure		400				{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/volume/create [POST]
+func createVolume(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.VolumeMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		if ok, code := checkVolumeMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		uid, ok := db.CreateVolume(novelID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(
+			createVolumeResult{
+				VolumeID: hex.EncodeToString(uid),
+			})
+	}
+}
+
+// Update Volume Metadata
+//
+//	@Summary		Update the volume metadata with the provided metadata
+//	@Description	Only the author of the novel can update volumes, possible error code: BadInput, TitleTooLong, TaglineTooLong, DescriptionTooLong
+//	@Tags			volume
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			VolumeID		path	string						true	"Volume ID"
+//	@Param			VolumeDetails	body	model.VolumeMetadata		true	"Volume details"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/volume/:volumeID [PATCH]
+func updateVolumeMetadata(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input model.VolumeMetadata
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+
+		if ok, code := checkVolumeMetadata(&input); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(code))
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		volumeID, status := getAuthorsVolume(c, db, session)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		ok = db.UpdateVolumeMetadata(volumeID, &input)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Delete Volume
+//
+//	@Summary		Delete the volume with the provided volume id along with all of its chapters
+//	@Description	Only the author of the novel can delete volumes
+//	@Tags			volume
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			VolumeID		path	string						true	"Volume ID"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/volume/:volumeID [DELETE]
+func deleteVolume(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		volumeID, status := getAuthorsVolume(c, db, session)
+		if status != fiber.StatusOK {
+			return c.SendStatus(status)
+		}
+
+		ok = db.DeleteVolume(volumeID)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Reorder Volumes
+//
+//	@Summary		Rewrite the reading order of the volumes in the novel
+//	@Description	The list must contain the id of every volume in the novel exactly once, only the author of the novel can reorder volumes, possible error code: BadInput, BadOrder
+//	@Tags			volume
+//	@Accept			json
+//	@Param			NovelID			path	string						true	"Novel ID"
+//	@Param			order			body	reorderInput				true	"Volume ids in the new order"
+//	@Param			sessionString	body	model.IncludeSessionString	true	"User's Session"
+//	@Success		200
+//	@Failure		400	{object}	ErrorJSON
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/novel/:novelID/volume/order [PATCH]
+func reorderVolumes(db model.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals(middleware.KeyIsUserAuth) == false {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		var input reorderInput
+		err := c.BodyParser(&input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadInput))
+		}
+		volumeIDs, ok := decodeIDs(input.IDs)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadOrder))
+		}
+
+		novelID, ok := getIDParam(c, "novelID")
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		session, ok := c.Locals(middleware.KeyUserSession).(model.Session)
+		if !ok {
+			log.Warn("Check the authentication middleware")
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		novel, ok := db.GetNovel(novelID)
+		if !ok {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if bytes.Compare(session.UserID, novel.Author) != 0 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		volumes := db.GetNovelVolumes(novelID, true)
+		existing := make([]string, 0, len(volumes))
+		for _, volume := range volumes {
+			existing = append(existing, volume.ID)
+		}
+		if !isSameIDList(input.IDs, existing) {
+			return c.Status(fiber.StatusBadRequest).JSON(buildErrorJSON(BadOrder))
+		}
+
+		ok = db.ReorderVolumes(novelID, volumeIDs)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
+
+// Return the novel and the volume from the path if the volume belongs to the novel,
+// otherwise return the status code to response with
+func getNovelAndVolume(c *fiber.Ctx, db model.DB) (model.Novel, model.Volume, int) {
+	novelID, ok := getIDParam(c, "novelID")
+	if !ok {
+		return model.Novel{}, model.Volume{}, fiber.StatusNotFound
+	}
+	volumeID, ok := getIDParam(c, "volumeID")
+	if !ok {
+		return model.Novel{}, model.Volume{}, fiber.StatusNotFound
+	}
+
+	novel, ok := db.GetNovel(novelID)
+	if !ok {
+		return model.Novel{}, model.Volume{}, fiber.StatusNotFound
+	}
+	volume, ok := db.GetVolume(volumeID)
+	if !ok || bytes.Compare(volume.NovelID, novelID) != 0 {
+		return model.Novel{}, model.Volume{}, fiber.StatusNotFound
+	}
+
+	return novel, volume, fiber.StatusOK
+}
+
+// Return the volume ID from the path if the volume belongs to the novel in the path
+// and the session user is the author of the novel, otherwise return the status code
+// to response with
+func getAuthorsVolume(c *fiber.Ctx, db model.DB, session model.Session) ([]byte, int) {
+	novel, volume, status := getNovelAndVolume(c, db)
+	if status != fiber.StatusOK {
+		return nil, status
+	}
+	if bytes.Compare(session.UserID, novel.Author) != 0 {
+		return nil, fiber.StatusUnauthorized
+	}
+
+	return volume.ID, fiber.StatusOK
+}
+
+func checkVolumeMetadata(input *model.VolumeMetadata) (bool, ErrorCode) {
+	if utf8.RuneCountInString(input.Title) > model.TitleMaxLength {
+		return false, TitleTooLong
+	}
+
+	if utf8.RuneCountInString(input.Tagline) > model.TaglineMaxLength {
+		return false, TaglineTooLong
+	}
+
+	if utf8.RuneCountInString(input.Description) > model.DescriptionMaxLength {
+		return false, DescriptionTooLong
+	}
+
+	if input.Visibility.String() == model.Unknown {
+		return false, BadInput
+	}
+
+	return true, BadInput
+}
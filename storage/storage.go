@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+)
+
+var (
+	ErrNotFound = errors.New("the object does not exist")
+	ErrBadKey   = errors.New("the key contains characters other than letters, digits, dot, dash and underscore")
+)
+
+// Storage keeps uploaded files as flat objects addressed by their key
+type Storage interface {
+	Save(ctx context.Context, key string, contentType string, data []byte) error
+	// Open the object for reading, ErrNotFound is returned if there is no such object
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete the object, deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+var keyRegex = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// Check if the key is safe to be used as a file name and an URL path segment
+func IsKeyValid(key string) bool {
+	return len(key) <= 255 && keyRegex.MatchString(key)
+}
@@ -0,0 +1,96 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var errMalformed = errors.New("the image is malformed")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// The PNG chunks which carry metadata instead of pixels
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// Remove the EXIF and other metadata from the JPEG or PNG image without
+// re-encoding its pixels, other images are returned unchanged
+func StripMetadata(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	default:
+		return data, nil
+	}
+}
+
+// Drop the APP1 to APP15 segments, which hold EXIF, XMP and the like, and the
+// comments. APP0 (JFIF) and APP14 (Adobe) are kept as they affect decoding
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errMalformed
+	}
+	res := bytes.NewBuffer(make([]byte, 0, len(data)))
+	res.Write(data[:2])
+
+	i := 2
+	for {
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, errMalformed
+		}
+		marker := data[i+1]
+		// Start of scan, the rest is the compressed image
+		if marker == 0xDA {
+			res.Write(data[i:])
+			return res.Bytes(), nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, errMalformed
+		}
+		isMetadata := (marker >= 0xE1 && marker <= 0xEF && marker != 0xEE) || marker == 0xFE
+		if !isMetadata {
+			res.Write(data[i:end])
+		}
+		i = end
+	}
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errMalformed
+	}
+	res := bytes.NewBuffer(make([]byte, 0, len(data)))
+	res.Write(pngSignature)
+
+	i := len(pngSignature)
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, errMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		chunkType := string(data[i+4 : i+8])
+		// Length, type, data and CRC
+		end := i + 12 + length
+		if length < 0 || end > len(data) || end < i {
+			return nil, errMalformed
+		}
+		if !pngMetadataChunks[chunkType] {
+			res.Write(data[i:end])
+		}
+		i = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return res.Bytes(), nil
+}
@@ -0,0 +1,33 @@
+// Package thumbnail creates the resized variants of the uploaded images
+package thumbnail
+
+import (
+	"Lightnovel/model"
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+const jpegQuality = 85
+
+// Decode the image and encode every variant in model.ImageVariantList as JPEG
+func Generate(data []byte) (map[model.ImageVariant][]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	flat := flatten(src)
+	variants := make(map[model.ImageVariant][]byte, len(model.ImageVariantList))
+	for _, variant := range model.ImageVariantList {
+		width, height := fitSize(flat.Bounds().Dx(), flat.Bounds().Dy(), variant.MaxSize())
+		var buf bytes.Buffer
+		err := jpeg.Encode(&buf, resize(flat, width, height), &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return nil, err
+		}
+		variants[variant] = buf.Bytes()
+	}
+	return variants, nil
+}
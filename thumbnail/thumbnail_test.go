@@ -0,0 +1,130 @@
+package thumbnail
+
+import (
+	"Lightnovel/model"
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func Test_fitSize(t *testing.T) {
+	tests := []struct {
+		width, height, maxSize int
+		wantWidth, wantHeight  int
+	}{
+		{100, 50, 160, 100, 50},
+		{1600, 800, 160, 160, 80},
+		{800, 1600, 160, 80, 160},
+		{1000, 1, 160, 160, 1},
+	}
+	for _, tt := range tests {
+		width, height := fitSize(tt.width, tt.height, tt.maxSize)
+		if width != tt.wantWidth || height != tt.wantHeight {
+			t.Errorf(
+				"fitSize(%v, %v, %v) = %v, %v, want %v, %v",
+				tt.width, tt.height, tt.maxSize, width, height, tt.wantWidth, tt.wantHeight,
+			)
+		}
+	}
+}
+
+func Test_resize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.RGBA{R: 200, A: 255})
+	src.Set(1, 0, color.RGBA{R: 100, A: 255})
+	src.Set(0, 1, color.RGBA{G: 40, A: 255})
+	src.Set(1, 1, color.RGBA{B: 80, A: 255})
+
+	dst := resize(src, 1, 1)
+	want := color.RGBA{R: 75, G: 10, B: 20, A: 255}
+	if got := dst.RGBAAt(0, 0); got != want {
+		t.Errorf("resize() = %v, want %v", got, want)
+	}
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 600; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func TestGenerate(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	variants, err := Generate(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantWidths := map[model.ImageVariant]int{
+		model.ImageSmall:  160,
+		model.ImageMedium: 480,
+		model.ImageLarge:  600,
+	}
+	for variant, wantWidth := range wantWidths {
+		config, err := jpeg.DecodeConfig(bytes.NewReader(variants[variant]))
+		if err != nil {
+			t.Fatalf("Generate() %v: %v", variant, err)
+		}
+		if config.Width != wantWidth || config.Height != wantWidth/2 {
+			t.Errorf("Generate() %v = %vx%v, want %vx%v", variant, config.Width, config.Height, wantWidth, wantWidth/2)
+		}
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	var jpegBuf bytes.Buffer
+	if err := jpeg.Encode(&jpegBuf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	// Insert an EXIF segment and a comment right after SOI
+	exif := []byte{0xFF, 0xE1, 0x00, 0x0C, 'E', 'x', 'i', 'f', 0, 0, 'G', 'P', 'S', '!'}
+	comment := []byte{0xFF, 0xFE, 0x00, 0x06, 'n', 'o', 't', 'e'}
+	jpegData := append([]byte{0xFF, 0xD8}, exif...)
+	jpegData = append(jpegData, comment...)
+	jpegData = append(jpegData, jpegBuf.Bytes()[2:]...)
+
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	// Insert a tEXt chunk right after IHDR, the CRC is not checked before IEND
+	text := []byte{0, 0, 0, 4, 't', 'E', 'X', 't', 'G', 'P', 'S', '!', 0, 0, 0, 0}
+	ihdrEnd := len(pngSignature) + 25
+	pngData := append([]byte{}, pngBuf.Bytes()[:ihdrEnd]...)
+	pngData = append(pngData, text...)
+	pngData = append(pngData, pngBuf.Bytes()[ihdrEnd:]...)
+
+	tests := []struct {
+		contentType string
+		data        []byte
+	}{
+		{"image/jpeg", jpegData},
+		{"image/png", pngData},
+	}
+	for _, tt := range tests {
+		got, err := StripMetadata(tt.data, tt.contentType)
+		if err != nil {
+			t.Fatalf("StripMetadata(%v) error = %v", tt.contentType, err)
+		}
+		if bytes.Contains(got, []byte("GPS!")) || bytes.Contains(got, []byte("note")) {
+			t.Errorf("StripMetadata(%v) kept the metadata", tt.contentType)
+		}
+		if _, _, err := image.Decode(bytes.NewReader(got)); err != nil {
+			t.Errorf("StripMetadata(%v) cannot be decoded: %v", tt.contentType, err)
+		}
+	}
+
+	if _, err := StripMetadata([]byte("not an image"), "image/png"); err == nil {
+		t.Errorf("StripMetadata() of a malformed image succeeded")
+	}
+}
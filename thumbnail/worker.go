@@ -0,0 +1,89 @@
+package thumbnail
+
+import (
+	"Lightnovel/model"
+	"Lightnovel/storage"
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"io"
+	"time"
+)
+
+const storageTimeout = time.Minute
+
+// Worker generates the variants of the uploaded images in the background
+type Worker struct {
+	db    model.DB
+	store storage.Storage
+	jobs  chan string
+}
+
+func NewWorker(db model.DB, store storage.Storage, queueSize int) *Worker {
+	return &Worker{
+		db:    db,
+		store: store,
+		jobs:  make(chan string, queueSize),
+	}
+}
+
+// Queue the image with the storage key, return false if the queue is full.
+// The image is picked up by EnqueuePending later in that case
+func (w *Worker) Enqueue(key string) bool {
+	select {
+	case w.jobs <- key:
+		return true
+	default:
+		return false
+	}
+}
+
+// Queue the images whose variants are not generated yet, a failed image is
+// retried on every call
+func (w *Worker) EnqueuePending() bool {
+	keys, ok := w.db.GetPendingImageKeys()
+	if !ok {
+		return false
+	}
+	for _, key := range keys {
+		if !w.Enqueue(key) {
+			break
+		}
+	}
+	return true
+}
+
+// Process the queued images until the program exits
+func (w *Worker) Run() {
+	for key := range w.jobs {
+		if err := w.process(key); err != nil {
+			log.Error(err)
+			continue
+		}
+		w.db.SetImageVariantsReady(key)
+	}
+}
+
+func (w *Worker) process(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+	defer cancel()
+	reader, err := w.store.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		return err
+	}
+
+	variants, err := Generate(data)
+	if err != nil {
+		return err
+	}
+	for variant, variantData := range variants {
+		if err := w.store.Save(ctx, variant.Key(key), "image/jpeg", variantData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
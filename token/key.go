@@ -0,0 +1,101 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	AlgorithmHMAC    = "HS256"
+	AlgorithmEd25519 = "EdDSA"
+
+	// The shortest HMAC secret accepted, the size of the SHA-256 output
+	hmacMinSecretSize = sha256.Size
+)
+
+var ErrBadKeySpec = errors.New("the key list must be id:base64 pairs separated by commas")
+
+// A key signing and verifying the tokens, the ID is sent in the kid header so
+// tokens signed by a retired key can still be verified during the rotation
+type Key struct {
+	ID        string
+	Algorithm string
+
+	secret  []byte
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+func NewHMACKey(id string, secret []byte) (Key, error) {
+	if len(secret) < hmacMinSecretSize {
+		return Key{}, fmt.Errorf("the HMAC secret %q must be at least %v bytes", id, hmacMinSecretSize)
+	}
+	return Key{ID: id, Algorithm: AlgorithmHMAC, secret: secret}, nil
+}
+
+// Create the Ed25519 key from its 32 bytes seed
+func NewEd25519Key(id string, seed []byte) (Key, error) {
+	if len(seed) != ed25519.SeedSize {
+		return Key{}, fmt.Errorf("the Ed25519 seed %q must be %v bytes", id, ed25519.SeedSize)
+	}
+	private := ed25519.NewKeyFromSeed(seed)
+	return Key{
+		ID:        id,
+		Algorithm: AlgorithmEd25519,
+		private:   private,
+		public:    private.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+func (k *Key) sign(data []byte) []byte {
+	if k.Algorithm == AlgorithmEd25519 {
+		return ed25519.Sign(k.private, data)
+	}
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (k *Key) verify(data []byte, signature []byte) bool {
+	if k.Algorithm == AlgorithmEd25519 {
+		return ed25519.Verify(k.public, data, signature)
+	}
+	return hmac.Equal(k.sign(data), signature)
+}
+
+// Parse the keys of the algorithm from a list of id:base64 pairs separated by
+// commas, the base64 is the HMAC secret or the Ed25519 seed. The first key signs
+// the new tokens, the others are only used to verify the tokens they signed
+func ParseKeys(algorithm string, spec string) ([]Key, error) {
+	var keys []Key
+	for _, pair := range strings.Split(spec, ",") {
+		id, encoded, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || id == "" {
+			return nil, ErrBadKeySpec
+		}
+		material, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, ErrBadKeySpec
+		}
+
+		var key Key
+		switch algorithm {
+		case AlgorithmHMAC:
+			key, err = NewHMACKey(id, material)
+		case AlgorithmEd25519:
+			key, err = NewEd25519Key(id, material)
+		default:
+			err = fmt.Errorf("unknown algorithm %q, use %v or %v", algorithm, AlgorithmHMAC, AlgorithmEd25519)
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,138 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// How long an access token is accepted, the client renews it with the refresh token
+const AccessTokenDuration = 15 * time.Minute
+
+var (
+	ErrMalformed    = errors.New("the token is not a well formed JWT")
+	ErrUnknownKey   = errors.New("the token is signed by an unknown key")
+	ErrBadSignature = errors.New("the token signature does not match")
+	ErrExpired      = errors.New("the token is expired")
+	ErrNoKey        = errors.New("the issuer needs at least one key")
+)
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// The claims of an access token, the IDs are hex encoded
+type Claims struct {
+	// The user the token was issued to
+	Subject string `json:"sub"`
+	// The session the token was issued with
+	SessionID string `json:"sid"`
+	Role      string `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Issuer signs the access tokens with its current key and verifies them with any
+// of its keys
+type Issuer struct {
+	current Key
+	keys    map[string]Key
+	now     func() time.Time
+}
+
+// Create the issuer, the first key signs the tokens
+func NewIssuer(keys ...Key) (*Issuer, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKey
+	}
+	issuer := &Issuer{
+		current: keys[0],
+		keys:    make(map[string]Key, len(keys)),
+		now:     time.Now,
+	}
+	for _, key := range keys {
+		issuer.keys[key.ID] = key
+	}
+	return issuer, nil
+}
+
+// Sign a token for the claims valid for AccessTokenDuration,
+// the issue and expire time of the claims are overwritten
+func (i *Issuer) Issue(claims Claims) (string, time.Time, error) {
+	now := i.now()
+	expires := now.Add(AccessTokenDuration)
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = expires.Unix()
+
+	headerJSON, err := json.Marshal(header{
+		Algorithm: i.current.Algorithm,
+		Type:      "JWT",
+		KeyID:     i.current.ID,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signed := encode(headerJSON) + "." + encode(claimsJSON)
+	signature := i.current.sign([]byte(signed))
+	return signed + "." + encode(signature), expires, nil
+}
+
+// Return the claims of the token if it is signed by one of the keys and not expired
+func (i *Issuer) Verify(token string) (Claims, error) {
+	var claims Claims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, ErrMalformed
+	}
+
+	headerJSON, err := decode(parts[0])
+	if err != nil {
+		return claims, ErrMalformed
+	}
+	var h header
+	if err = json.Unmarshal(headerJSON, &h); err != nil {
+		return claims, ErrMalformed
+	}
+	key, ok := i.keys[h.KeyID]
+	// The algorithm must be the one of the key so a token cannot pick a weaker one
+	if !ok || key.Algorithm != h.Algorithm {
+		return claims, ErrUnknownKey
+	}
+
+	signature, err := decode(parts[2])
+	if err != nil {
+		return claims, ErrMalformed
+	}
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return claims, ErrBadSignature
+	}
+
+	claimsJSON, err := decode(parts[1])
+	if err != nil {
+		return claims, ErrMalformed
+	}
+	if err = json.Unmarshal(claimsJSON, &claims); err != nil {
+		return claims, ErrMalformed
+	}
+	if i.now().Unix() >= claims.ExpiresAt {
+		return claims, ErrExpired
+	}
+	return claims, nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
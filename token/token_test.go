@@ -0,0 +1,131 @@
+package token
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKeys(t *testing.T) (Key, Key, Key) {
+	hmacKey, err := NewHMACKey("h1", bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherHMACKey, err := NewHMACKey("h2", bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, err := NewEd25519Key("e1", bytes.Repeat([]byte{3}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hmacKey, otherHMACKey, edKey
+}
+
+func TestIssuer(t *testing.T) {
+	hmacKey, otherHMACKey, edKey := testKeys(t)
+	claims := Claims{Subject: "user", SessionID: "session", Role: "reader"}
+
+	for _, key := range []Key{hmacKey, edKey} {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			issuer, err := NewIssuer(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, expires, err := issuer.Issue(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := issuer.Verify(token)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got.Subject != claims.Subject || got.SessionID != claims.SessionID ||
+				got.Role != claims.Role || got.ExpiresAt != expires.Unix() {
+				t.Errorf("Verify() = %v, want %v", got, claims)
+			}
+
+			parts := strings.Split(token, ".")
+			tampered := parts[0] + "." + encode([]byte(`{"sub":"admin","exp":9999999999}`)) + "." + parts[2]
+			if _, err := issuer.Verify(tampered); err != ErrBadSignature {
+				t.Errorf("Verify(tampered) error = %v, want %v", err, ErrBadSignature)
+			}
+		})
+	}
+
+	t.Run("Rotation", func(t *testing.T) {
+		oldIssuer, _ := NewIssuer(hmacKey)
+		token, _, _ := oldIssuer.Issue(claims)
+
+		rotated, _ := NewIssuer(otherHMACKey, hmacKey)
+		if _, err := rotated.Verify(token); err != nil {
+			t.Errorf("Verify() with the retired key error = %v", err)
+		}
+		dropped, _ := NewIssuer(otherHMACKey)
+		if _, err := dropped.Verify(token); err != ErrUnknownKey {
+			t.Errorf("Verify() with the dropped key error = %v, want %v", err, ErrUnknownKey)
+		}
+	})
+
+	t.Run("Algorithm confusion", func(t *testing.T) {
+		issuer, _ := NewIssuer(edKey)
+		forged := encode([]byte(`{"alg":"HS256","typ":"JWT","kid":"e1"}`)) + "." + encode([]byte(`{"sub":"admin"}`))
+		if _, err := issuer.Verify(forged + "." + encode([]byte("signature"))); err != ErrUnknownKey {
+			t.Errorf("Verify() error = %v, want %v", err, ErrUnknownKey)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		issuer, _ := NewIssuer(hmacKey)
+		token, _, _ := issuer.Issue(claims)
+		issuer.now = func() time.Time { return time.Now().Add(AccessTokenDuration) }
+		if _, err := issuer.Verify(token); err != ErrExpired {
+			t.Errorf("Verify() error = %v, want %v", err, ErrExpired)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		issuer, _ := NewIssuer(hmacKey)
+		for _, token := range []string{"", "a.b", "a.b.c", "0123456789abcdef0123456789abcdef"} {
+			if _, err := issuer.Verify(token); err == nil {
+				t.Errorf("Verify(%q) error = nil", token)
+			}
+		}
+	})
+}
+
+func TestParseKeys(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	tests := []struct {
+		name      string
+		algorithm string
+		spec      string
+		wantIDs   []string
+		wantErr   bool
+	}{
+		{"HMAC", AlgorithmHMAC, "new:" + secret + ", old:" + secret, []string{"new", "old"}, false},
+		{"Ed25519", AlgorithmEd25519, "k:" + secret, []string{"k"}, false},
+		{"Short secret", AlgorithmHMAC, "k:" + base64.StdEncoding.EncodeToString([]byte("short")), nil, true},
+		{"Missing id", AlgorithmHMAC, ":" + secret, nil, true},
+		{"Bad base64", AlgorithmHMAC, "k:***", nil, true},
+		{"Unknown algorithm", "RS256", "k:" + secret, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseKeys(tt.algorithm, tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(keys) != len(tt.wantIDs) {
+				t.Fatalf("ParseKeys() returned %v keys, want %v", len(keys), len(tt.wantIDs))
+			}
+			for i, key := range keys {
+				if key.ID != tt.wantIDs[i] {
+					t.Errorf("ParseKeys()[%v].ID = %v, want %v", i, key.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
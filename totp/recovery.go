@@ -0,0 +1,48 @@
+package totp
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+const (
+	RecoveryCodeCount = 10
+	// The length of a recovery code without the dash
+	recoveryCodeLength = 10
+)
+
+// Letters and digits without the ones easily mistaken for each other
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// Generate the one-time codes used when the authenticator app is lost, they are
+// shown as two groups of five characters
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		raw := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		var builder strings.Builder
+		for j, b := range raw {
+			if j == recoveryCodeLength/2 {
+				builder.WriteByte('-')
+			}
+			// 256 is not a multiple of the alphabet size, the bias is too small to matter
+			builder.WriteByte(recoveryAlphabet[int(b)%len(recoveryAlphabet)])
+		}
+		codes = append(codes, builder.String())
+	}
+	return codes, nil
+}
+
+// Return the recovery code the way it was generated, users may type it in
+// upper case, without the dash or with spaces
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	if len(code) != recoveryCodeLength {
+		return ""
+	}
+	return code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+}
@@ -0,0 +1,94 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 test vectors of RFC 6238 appendix B, truncated to 6 digits
+func TestCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := Code(secret, Counter(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("Code(%v) = %v, want %v", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"Current", "081804", true},
+		{"Previous period", Code(secret, Counter(now)-1), true},
+		{"Next period", Code(secret, Counter(now)+1), true},
+		{"Too old", Code(secret, Counter(now)-2), false},
+		{"Wrong", "000000", false},
+		{"Short", "08180", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := Validate(secret, tt.code, now)
+			if ok != tt.ok {
+				t.Fatalf("Validate() = %v, want %v", ok, tt.ok)
+			}
+			if ok && Code(secret, counter) != tt.code {
+				t.Errorf("Validate() returned the time step %v which does not match the code", counter)
+			}
+		})
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	got := ProvisioningURI("Light novel", "reader", []byte("12345678901234567890"))
+	want := "otpauth://totp/Light%20novel:reader?algorithm=SHA1&digits=6&issuer=Light+novel&period=30" +
+		"&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	if got != want {
+		t.Errorf("ProvisioningURI() = %v, want %v", got, want)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("got %v codes, want %v", len(codes), RecoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if NormalizeRecoveryCode(code) != code {
+			t.Errorf("NormalizeRecoveryCode(%q) changed a generated code", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+
+	code := codes[0]
+	typed := strings.ToUpper(strings.Replace(code, "-", " ", 1))
+	if got := NormalizeRecoveryCode(typed); got != code {
+		t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", typed, got, code)
+	}
+	if got := NormalizeRecoveryCode("abc"); got != "" {
+		t.Errorf("NormalizeRecoveryCode(%q) = %q, want empty", "abc", got)
+	}
+}